@@ -0,0 +1,119 @@
+package waitfor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// HTTPAuth describes how to authenticate requests to an HTTP target, using either basic auth
+// or a bearer token. Secrets can be read from environment variables or files so that they
+// don't have to be written in to the config or the target URL.
+type HTTPAuth struct {
+	// Username is sent with basic auth
+	Username string
+	// Password is sent with basic auth
+	Password string
+	// PasswordEnv is the name of an environment variable holding the password
+	PasswordEnv string `yaml:"password-env"`
+	// PasswordFile is a file holding the password, read before each attempt
+	PasswordFile string `yaml:"password-file"`
+	// Token is sent as a bearer token
+	Token string
+	// TokenEnv is the name of an environment variable holding the bearer token
+	TokenEnv string `yaml:"token-env"`
+	// TokenFile is a file holding the bearer token, read before each attempt so that tokens
+	// that are rotated are picked up
+	TokenFile string `yaml:"token-file"`
+}
+
+func (a *HTTPAuth) basic() bool {
+	return a.Username != "" || a.Password != "" || a.PasswordEnv != "" || a.PasswordFile != ""
+}
+
+func (a *HTTPAuth) bearer() bool {
+	return a.Token != "" || a.TokenEnv != "" || a.TokenFile != ""
+}
+
+// check makes sure that the settings make sense without reading any secrets
+func (a *HTTPAuth) check() error {
+	if a == nil {
+		return nil
+	}
+	if a.basic() && a.bearer() {
+		return errors.New("auth can use a username and password or a token, not both")
+	}
+	if a.basic() && a.Username == "" {
+		return errors.New("auth needs a username to go with the password")
+	}
+	if countSet(a.Password, a.PasswordEnv, a.PasswordFile) > 1 {
+		return errors.New("auth can only use one of password, password-env or password-file")
+	}
+	if countSet(a.Token, a.TokenEnv, a.TokenFile) > 1 {
+		return errors.New("auth can only use one of token, token-env or token-file")
+	}
+	return nil
+}
+
+// apply adds the credentials to req, replacing any Authorization header that is already set
+func (a *HTTPAuth) apply(req *http.Request) error {
+	if a == nil {
+		return nil
+	}
+	if err := a.check(); err != nil {
+		return err
+	}
+
+	switch {
+	case a.basic():
+		password, err := secret("password", a.Password, a.PasswordEnv, a.PasswordFile)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(a.Username, password)
+	case a.bearer():
+		token, err := secret("token", a.Token, a.TokenEnv, a.TokenFile)
+		if err != nil {
+			return err
+		}
+		if token == "" {
+			return errors.New("auth token is empty")
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// secret finds the value of a secret given directly, in an environment variable or in a
+// file. Whitespace around values from files is removed as they usually end with a newline.
+// The value is never included in any errors.
+func secret(name, value, env, file string) (string, error) {
+	switch {
+	case env != "":
+		value, found := os.LookupEnv(env)
+		if !found {
+			return "", fmt.Errorf("auth %s environment variable %s isn't set", name, env)
+		}
+		return value, nil
+	case file != "":
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("unable to read auth %s: %w", name, err)
+		}
+		return strings.TrimSpace(string(contents)), nil
+	default:
+		return value, nil
+	}
+}
+
+func countSet(values ...string) int {
+	count := 0
+	for _, v := range values {
+		if v != "" {
+			count++
+		}
+	}
+	return count
+}
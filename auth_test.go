@@ -0,0 +1,118 @@
+package waitfor
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func authorization(t *testing.T, auth *HTTPAuth) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, "http://localhost/health", nil)
+	require.NoError(t, err)
+
+	err = auth.apply(req)
+	return req.Header.Get("Authorization"), err
+}
+
+func TestHTTPAuth_noAuthWithoutSettings(t *testing.T) {
+	header, err := authorization(t, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "", header)
+}
+
+func TestHTTPAuth_basic(t *testing.T) {
+	header, err := authorization(t, &HTTPAuth{Username: "user", Password: "pass"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Basic dXNlcjpwYXNz", header)
+}
+
+func TestHTTPAuth_basicPasswordFromEnvAndFile(t *testing.T) {
+	t.Setenv("WAIT_FOR_TEST_PASSWORD", "pass")
+	path := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(path, []byte("pass\n"), 0o600))
+
+	header, err := authorization(t, &HTTPAuth{Username: "user", PasswordEnv: "WAIT_FOR_TEST_PASSWORD"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Basic dXNlcjpwYXNz", header)
+
+	header, err = authorization(t, &HTTPAuth{Username: "user", PasswordFile: path})
+	assert.NoError(t, err)
+	assert.Equal(t, "Basic dXNlcjpwYXNz", header)
+}
+
+func TestHTTPAuth_bearer(t *testing.T) {
+	header, err := authorization(t, &HTTPAuth{Token: "abc123"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer abc123", header)
+}
+
+func TestHTTPAuth_bearerFromEnv(t *testing.T) {
+	t.Setenv("WAIT_FOR_TEST_TOKEN", "abc123")
+
+	header, err := authorization(t, &HTTPAuth{TokenEnv: "WAIT_FOR_TEST_TOKEN"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer abc123", header)
+
+	_, err = authorization(t, &HTTPAuth{TokenEnv: "WAIT_FOR_TEST_MISSING_TOKEN"})
+	assert.EqualError(t, err, "auth token environment variable WAIT_FOR_TEST_MISSING_TOKEN isn't set")
+}
+
+func TestHTTPAuth_bearerFileIsReadEachTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	auth := &HTTPAuth{TokenFile: path}
+
+	_, err := authorization(t, auth)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0o600))
+	header, err := authorization(t, auth)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer first", header)
+
+	require.NoError(t, os.WriteFile(path, []byte("second\n"), 0o600))
+	header, err = authorization(t, auth)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer second", header)
+
+	require.NoError(t, os.WriteFile(path, []byte("\n"), 0o600))
+	_, err = authorization(t, auth)
+	assert.EqualError(t, err, "auth token is empty")
+}
+
+func TestHTTPAuth_failsForBadSettings(t *testing.T) {
+	tests := map[string]struct {
+		auth     HTTPAuth
+		expected string
+	}{
+		"basic and bearer":      {auth: HTTPAuth{Username: "user", Token: "abc"}, expected: "auth can use a username and password or a token, not both"},
+		"password without user": {auth: HTTPAuth{PasswordEnv: "PASSWORD"}, expected: "auth needs a username to go with the password"},
+		"several passwords":     {auth: HTTPAuth{Username: "user", Password: "pass", PasswordFile: "password"}, expected: "auth can only use one of password, password-env or password-file"},
+		"several tokens":        {auth: HTTPAuth{TokenEnv: "TOKEN", TokenFile: "token"}, expected: "auth can only use one of token, token-env or token-file"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := test.auth.check()
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+func TestHTTPWaiter_sendsAuth(t *testing.T) {
+	recorder := &requestRecorder{}
+	server := recorder.server(t, http.StatusOK)
+
+	err := HTTPWaiterContext(context.Background(), "server", &TargetConfig{
+		Target:        server.URL,
+		StatusPattern: DefaultStatusPattern,
+		HTTPHeaders:   map[string]string{"Authorization": "Bearer old"},
+		Auth:          &HTTPAuth{Token: "abc123"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer abc123", recorder.header.Get("Authorization"))
+}
@@ -0,0 +1,105 @@
+package waitfor
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Backoff decides how long to pause between attempts to reach a target
+type Backoff interface {
+	// Next returns the pause to take after the given number of failed attempts
+	Next(failures int) time.Duration
+}
+
+// BackoffFunc is used to implement a Backoff with a single function
+type BackoffFunc func(failures int) time.Duration
+
+func (b BackoffFunc) Next(failures int) time.Duration {
+	return b(failures)
+}
+
+// BackoffStrategy creates a new Backoff for a target. The interval is the base pause
+// between attempts and limit is the longest pause that the strategy should return.
+type BackoffStrategy func(interval, limit time.Duration) Backoff
+
+// SupportedBackoffs is a mapping of the backoff names that can be used in config to their
+// implementations
+var SupportedBackoffs = map[string]BackoffStrategy{
+	"constant":    ConstantBackoff,
+	"linear":      LinearBackoff,
+	"exponential": ExponentialBackoff,
+	"jitter":      DecorrelatedJitterBackoff,
+}
+
+// ConstantBackoff pauses for the same interval between every attempt
+func ConstantBackoff(interval, limit time.Duration) Backoff {
+	return BackoffFunc(func(int) time.Duration {
+		return interval
+	})
+}
+
+// LinearBackoff increases the pause by interval after every failed attempt, up to limit
+func LinearBackoff(interval, limit time.Duration) Backoff {
+	return BackoffFunc(func(failures int) time.Duration {
+		return capInterval(interval*time.Duration(failures), limit)
+	})
+}
+
+// ExponentialBackoff doubles the pause after every failed attempt, up to limit
+func ExponentialBackoff(interval, limit time.Duration) Backoff {
+	return BackoffFunc(func(failures int) time.Duration {
+		next := interval
+		for i := 1; i < failures; i++ {
+			next *= 2
+			if limit > 0 && next >= limit {
+				return limit
+			}
+		}
+		return capInterval(next, limit)
+	})
+}
+
+// DecorrelatedJitterBackoff picks a random pause between interval and 3 times the previous
+// pause, up to limit. This spreads out attempts when many clients are waiting on the same target.
+func DecorrelatedJitterBackoff(interval, limit time.Duration) Backoff {
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	last := interval
+	return BackoffFunc(func(int) time.Duration {
+		upper := last * 3
+		next := interval
+		if upper > interval {
+			next += time.Duration(random.Int63n(int64(upper - interval)))
+		}
+		last = capInterval(next, limit)
+		return last
+	})
+}
+
+func capInterval(d, limit time.Duration) time.Duration {
+	if limit > 0 && d > limit {
+		return limit
+	}
+	return d
+}
+
+// newBackoff creates the Backoff configured for target, falling back to the defaults
+// when the target doesn't specify its own settings
+func newBackoff(target *TargetConfig) (Backoff, error) {
+	name := target.Backoff
+	if name == "" {
+		name = DefaultBackoff
+	}
+
+	strategy, found := SupportedBackoffs[name]
+	if !found {
+		return nil, fmt.Errorf("unknown backoff %s", name)
+	}
+
+	limit := target.MaxInterval
+	if limit == 0 {
+		limit = DefaultMaxInterval
+	}
+
+	return strategy(target.interval(), limit), nil
+}
@@ -0,0 +1,76 @@
+package waitfor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstantBackoff(t *testing.T) {
+	b := ConstantBackoff(time.Second, time.Second*5)
+
+	assert.Equal(t, time.Second, b.Next(1))
+	assert.Equal(t, time.Second, b.Next(2))
+	assert.Equal(t, time.Second, b.Next(10))
+}
+
+func TestLinearBackoff(t *testing.T) {
+	b := LinearBackoff(time.Second, time.Second*5)
+
+	assert.Equal(t, time.Second, b.Next(1))
+	assert.Equal(t, time.Second*2, b.Next(2))
+	assert.Equal(t, time.Second*3, b.Next(3))
+	assert.Equal(t, time.Second*5, b.Next(10))
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff(time.Second, time.Second*10)
+
+	assert.Equal(t, time.Second, b.Next(1))
+	assert.Equal(t, time.Second*2, b.Next(2))
+	assert.Equal(t, time.Second*4, b.Next(3))
+	assert.Equal(t, time.Second*8, b.Next(4))
+	assert.Equal(t, time.Second*10, b.Next(5))
+	assert.Equal(t, time.Second*10, b.Next(100))
+}
+
+func TestDecorrelatedJitterBackoff(t *testing.T) {
+	b := DecorrelatedJitterBackoff(time.Second, time.Second*10)
+
+	last := time.Second
+	for i := 1; i < 50; i++ {
+		next := b.Next(i)
+		assert.GreaterOrEqual(t, next, time.Second)
+		assert.LessOrEqual(t, next, time.Second*10)
+		assert.LessOrEqual(t, next, last*3)
+		last = next
+	}
+}
+
+func TestNewBackoff_usesDefaults(t *testing.T) {
+	b, err := newBackoff(&TargetConfig{})
+
+	require.NoError(t, err)
+	assert.Equal(t, DefaultInterval, b.Next(5))
+}
+
+func TestNewBackoff_usesTargetSettings(t *testing.T) {
+	b, err := newBackoff(&TargetConfig{
+		Interval:    time.Millisecond * 100,
+		Backoff:     "exponential",
+		MaxInterval: time.Millisecond * 300,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, time.Millisecond*200, b.Next(2))
+	assert.Equal(t, time.Millisecond*300, b.Next(3))
+}
+
+func TestNewBackoff_failsForUnknownBackoff(t *testing.T) {
+	_, err := newBackoff(&TargetConfig{Backoff: "unknown"})
+
+	require.Error(t, err)
+	assert.Equal(t, "unknown backoff unknown", err.Error())
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	waitfor "github.com/dnnrly/wait-for"
+)
+
+// splitCommand separates the arguments for wait-for from the command to run once the
+// targets are ready, which follows the first --
+func splitCommand(args []string) ([]string, []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+	return args, nil
+}
+
+// shouldRunCommand decides whether the command is run after waiting finished with err
+func shouldRunCommand(err error, runOnTimeout bool) bool {
+	if err == nil {
+		return true
+	}
+
+	var timeoutErr *waitfor.TimeoutError
+	return runOnTimeout && errors.As(err, &timeoutErr)
+}
+
+var unsafeEnvChars = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// targetEnv creates environment variables describing each target so that the command can
+// find out what was ready
+func targetEnv(results []waitfor.Result) []string {
+	var names, ready []string
+	var env []string
+	for _, r := range results {
+		names = append(names, r.Name)
+		if r.Status == waitfor.StatusReady {
+			ready = append(ready, r.Name)
+		}
+
+		prefix := "WAIT_FOR_" + envName(r.Name)
+		env = append(env,
+			prefix+"_TYPE="+r.Type,
+			prefix+"_TARGET="+r.Target,
+			prefix+"_STATUS="+string(r.Status),
+		)
+	}
+
+	return append(env,
+		"WAIT_FOR_TARGETS="+strings.Join(names, ","),
+		"WAIT_FOR_READY="+strings.Join(ready, ","),
+	)
+}
+
+// envName converts a target name in to something that can be used in an environment variable
+func envName(name string) string {
+	return strings.Trim(unsafeEnvChars.ReplaceAllString(strings.ToUpper(name), "_"), "_")
+}
+
+// checkTargetEnv makes sure that no two of the targets named, the targets in any groups named
+// or the targets that they depend on would set the same environment variables
+func checkTargetEnv(config *waitfor.Config, names []string) error {
+	var targets []string
+	for _, name := range names {
+		if group, found := config.Groups[name]; found {
+			targets = append(targets, group.Targets...)
+		} else {
+			targets = append(targets, name)
+		}
+	}
+
+	unique := map[string]bool{}
+	for _, name := range targets {
+		unique[name] = true
+	}
+	for name := range config.Filter(targets).Targets {
+		unique[name] = true
+	}
+
+	var sorted []string
+	for name := range unique {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	seen := map[string]string{}
+	for _, name := range sorted {
+		env := envName(name)
+		if other, found := seen[env]; found {
+			return fmt.Errorf("targets %s and %s would both set the WAIT_FOR_%s_* environment variables", other, name, env)
+		}
+		seen[env] = name
+	}
+	return nil
+}
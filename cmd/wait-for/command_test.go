@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	waitfor "github.com/dnnrly/wait-for"
+)
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "HTTP_LOCALHOST_HEALTH", envName("http://localhost/health"))
+	assert.Equal(t, "TCP_DB_5432", envName("tcp:db:5432"))
+	assert.Equal(t, "API_1", envName("api-1"))
+}
+
+func TestCheckTargetEnv_acceptsDistinctNames(t *testing.T) {
+	config := waitfor.NewConfig()
+	config.Targets["api"] = waitfor.TargetConfig{Type: "http", DependsOn: []string{"db"}}
+	config.Targets["db"] = waitfor.TargetConfig{Type: "tcp"}
+
+	assert.NoError(t, checkTargetEnv(config, []string{"api", "tcp:cache:6379"}))
+}
+
+func TestCheckTargetEnv_failsWhenNamesCollide(t *testing.T) {
+	config := waitfor.NewConfig()
+	config.Targets["api-1"] = waitfor.TargetConfig{Type: "http"}
+	config.Targets["api.1"] = waitfor.TargetConfig{Type: "http"}
+	config.Targets["web"] = waitfor.TargetConfig{Type: "http", DependsOn: []string{"api.1"}}
+	config.Groups = map[string]waitfor.GroupConfig{
+		"apis": {Targets: []string{"api-1", "api.1"}},
+	}
+
+	expected := "targets api-1 and api.1 would both set the WAIT_FOR_API_1_* environment variables"
+	assert.EqualError(t, checkTargetEnv(config, []string{"api-1", "api.1"}), expected)
+	assert.EqualError(t, checkTargetEnv(config, []string{"apis"}), expected)
+	assert.EqualError(t, checkTargetEnv(config, []string{"api-1", "web"}), expected)
+	assert.NoError(t, checkTargetEnv(config, []string{"api-1"}))
+}
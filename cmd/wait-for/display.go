@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	waitfor "github.com/dnnrly/wait-for"
+)
+
+const (
+	displayRefresh  = 100 * time.Millisecond
+	displayErrorLen = 60
+)
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// isTerminal returns true if f is connected to a terminal rather than a file or pipe
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// displayRow is the latest that is known about a target
+type displayRow struct {
+	status   waitfor.Status
+	attempts int
+	lastErr  error
+	started  time.Time
+	elapsed  time.Duration
+}
+
+// liveDisplay draws a table with a row for each target that is redrawn in place as events
+// arrive, so that progress on many targets can be followed at a glance
+type liveDisplay struct {
+	lock  sync.Mutex
+	w     io.Writer
+	rows  map[string]*displayRow
+	lines int
+	frame int
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func newLiveDisplay(w io.Writer) *liveDisplay {
+	return &liveDisplay{
+		w:    w,
+		rows: map[string]*displayRow{},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// start redraws the table regularly until finish is called
+func (d *liveDisplay) start() {
+	go func() {
+		defer close(d.done)
+
+		ticker := time.NewTicker(displayRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.lock.Lock()
+				d.frame++
+				d.draw(false)
+				d.lock.Unlock()
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+}
+
+// event updates the row for a target, it is used as a waitfor.EventHandler
+func (d *liveDisplay) event(e waitfor.Event) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	row, found := d.rows[e.Name]
+	if !found {
+		row = &displayRow{started: e.Time}
+		d.rows[e.Name] = row
+	}
+
+	switch e.Type {
+	case waitfor.EventAttempt:
+		row.attempts = e.Attempt
+		if e.Err != nil {
+			row.lastErr = e.Err
+		}
+	case waitfor.EventStatus:
+		switch e.Status {
+		case waitfor.StatusPending:
+		case waitfor.StatusWaiting:
+			// Targets that had to wait for their dependencies are timed from when they're tried
+			if row.status == waitfor.StatusPending {
+				row.started = e.Time
+			}
+		default:
+			row.elapsed = e.Time.Sub(row.started)
+		}
+		row.status = e.Status
+	}
+}
+
+// finish stops redrawing and replaces the table with the final result for each target
+func (d *liveDisplay) finish(results []waitfor.Result) {
+	close(d.stop)
+	<-d.done
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, r := range results {
+		d.rows[r.Name] = &displayRow{
+			status:   r.Status,
+			attempts: r.Attempts,
+			lastErr:  r.LastError,
+			elapsed:  r.Duration,
+		}
+	}
+	d.draw(true)
+}
+
+// draw writes the table over the last one that was drawn
+func (d *liveDisplay) draw(final bool) {
+	var names []string
+	width := 0
+	for name := range d.rows {
+		names = append(names, name)
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	sort.Strings(names)
+
+	precision := displayRefresh
+	if final {
+		precision = time.Millisecond
+	}
+
+	var b strings.Builder
+	if d.lines > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", d.lines)
+	}
+	for _, name := range names {
+		row := d.rows[name]
+
+		elapsed := row.elapsed
+		if (row.status == waitfor.StatusWaiting || row.status == waitfor.StatusPending) && !final {
+			elapsed = time.Since(row.started)
+		}
+
+		lastErr := ""
+		if row.lastErr != nil && row.status != waitfor.StatusReady {
+			lastErr = truncate(row.lastErr.Error(), displayErrorLen)
+		}
+		if row.status == waitfor.StatusPending {
+			lastErr = "waiting for dependencies"
+		}
+
+		fmt.Fprintf(&b, "\x1b[2K%s %-*s  %-9s  %3d attempts  %8s  %s\n",
+			d.symbol(row.status), width, name, row.status, row.attempts, elapsed.Round(precision), lastErr)
+	}
+	d.lines = len(names)
+
+	_, _ = io.WriteString(d.w, b.String())
+}
+
+func (d *liveDisplay) symbol(status waitfor.Status) string {
+	switch status {
+	case waitfor.StatusReady:
+		return "✓"
+	case waitfor.StatusFailed:
+		return "✗"
+	case waitfor.StatusCancelled:
+		return "-"
+	}
+	return spinnerFrames[d.frame%len(spinnerFrames)]
+}
+
+func truncate(s string, length int) string {
+	runes := []rune(strings.ReplaceAll(s, "\n", " "))
+	if len(runes) <= length {
+		return string(runes)
+	}
+	return string(runes[:length-3]) + "..."
+}
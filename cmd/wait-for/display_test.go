@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	waitfor "github.com/dnnrly/wait-for"
+)
+
+// displayLines draws the display and splits what was written in to lines
+func displayLines(t *testing.T, d *liveDisplay, b *bytes.Buffer, final bool) []string {
+	b.Reset()
+	d.draw(final)
+	output := b.String()
+	require.True(t, strings.HasSuffix(output, "\n"))
+	return strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+}
+
+func TestLiveDisplay_showsEveryTargetFromTheStart(t *testing.T) {
+	var b bytes.Buffer
+	d := newLiveDisplay(&b)
+
+	now := time.Now()
+	d.event(waitfor.Event{Time: now, Type: waitfor.EventStatus, Name: "api", Status: waitfor.StatusPending})
+	d.event(waitfor.Event{Time: now, Type: waitfor.EventStatus, Name: "db", Status: waitfor.StatusWaiting})
+
+	assert.Equal(t, []string{
+		"\x1b[2K| api  pending      0 attempts        0s  waiting for dependencies",
+		"\x1b[2K| db   waiting      0 attempts        0s  ",
+	}, displayLines(t, d, &b, false))
+}
+
+func TestLiveDisplay_followsTargetsThroughToTheirResult(t *testing.T) {
+	var b bytes.Buffer
+	d := newLiveDisplay(&b)
+
+	start := time.Now().Add(-time.Second * 5)
+	d.event(waitfor.Event{Time: start, Type: waitfor.EventStatus, Name: "api", Status: waitfor.StatusPending})
+	d.event(waitfor.Event{Time: start, Type: waitfor.EventStatus, Name: "db", Status: waitfor.StatusWaiting})
+	d.event(waitfor.Event{Time: start.Add(time.Millisecond * 100), Type: waitfor.EventAttempt, Name: "db", Attempt: 1, Err: errors.New("connection refused")})
+	d.event(waitfor.Event{Time: start.Add(time.Millisecond * 1500), Type: waitfor.EventAttempt, Name: "db", Attempt: 2})
+	d.event(waitfor.Event{Time: start.Add(time.Millisecond * 1500), Type: waitfor.EventStatus, Name: "db", Status: waitfor.StatusReady})
+	d.event(waitfor.Event{Time: start.Add(time.Millisecond * 1500), Type: waitfor.EventStatus, Name: "api", Status: waitfor.StatusWaiting})
+	d.event(waitfor.Event{Time: start.Add(time.Millisecond * 1600), Type: waitfor.EventAttempt, Name: "api", Attempt: 1, Err: errors.New("503 status Code")})
+	d.event(waitfor.Event{Time: start.Add(time.Millisecond * 1800), Type: waitfor.EventStatus, Name: "api", Status: waitfor.StatusFailed})
+
+	assert.Equal(t, []string{
+		"\x1b[2K✗ api  failed       1 attempts     300ms  503 status Code",
+		"\x1b[2K✓ db   ready        2 attempts      1.5s  ",
+	}, displayLines(t, d, &b, false))
+
+	lines := displayLines(t, d, &b, false)
+	assert.True(t, strings.HasPrefix(lines[0], "\x1b[2A"), "redraws over the last table")
+}
+
+func TestLiveDisplay_finishShowsResults(t *testing.T) {
+	var b bytes.Buffer
+	d := newLiveDisplay(&b)
+
+	now := time.Now()
+	d.event(waitfor.Event{Time: now, Type: waitfor.EventStatus, Name: "api", Status: waitfor.StatusPending})
+	d.event(waitfor.Event{Time: now, Type: waitfor.EventStatus, Name: "db", Status: waitfor.StatusWaiting})
+
+	d.start()
+	d.finish([]waitfor.Result{
+		{Name: "api", Status: waitfor.StatusCancelled},
+		{Name: "db", Status: waitfor.StatusFailed, Attempts: 3, Duration: time.Millisecond * 1234, LastError: errors.New("connection refused")},
+	})
+
+	output := b.String()
+	lines := strings.Split(strings.TrimSuffix(output[strings.LastIndex(output, "\x1b[2K- api"):], "\n"), "\n")
+	assert.Equal(t, []string{
+		"\x1b[2K- api  cancelled    0 attempts        0s  ",
+		"\x1b[2K✗ db   failed       3 attempts    1.234s  connection refused",
+	}, lines)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 10))
+	assert.Equal(t, "two lines", truncate("two\nlines", 10))
+	assert.Equal(t, "a long ...", truncate("a long message", 10))
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// runCommand replaces wait-for with the command so that signals go straight to it. It only
+// returns if the command couldn't be started.
+func runCommand(command, env []string) error {
+	path, err := exec.LookPath(command[0])
+	if err != nil {
+		return err
+	}
+
+	return syscall.Exec(path, command, env)
+}
@@ -0,0 +1,32 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// runCommand runs the command and exits with its exit code, as Windows can't replace the
+// running process. It only returns if the command couldn't be started.
+func runCommand(command, env []string) error {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		return err
+	}
+
+	os.Exit(exitOK)
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	waitfor "github.com/dnnrly/wait-for"
+)
+
+// Exit codes that let scripts tell why wait-for failed
+const (
+	exitOK          = 0
+	exitFailed      = 1
+	exitUsage       = 2
+	exitConfig      = 3
+	exitTimeout     = 4
+	exitPartial     = 5
+	exitCannotRun   = 127
+	exitInterrupted = 130
+)
+
+// exitCode picks the exit code for the error returned from waiting
+func exitCode(err error) int {
+	var (
+		configErr      *waitfor.ConfigError
+		unknownErr     *waitfor.UnknownTypeError
+		invalidErr     *waitfor.InvalidTargetError
+		requirementErr *waitfor.RequirementError
+		timeoutErr     *waitfor.TimeoutError
+	)
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &configErr), errors.As(err, &unknownErr), errors.As(err, &invalidErr):
+		return exitConfig
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.As(err, &requirementErr) && requirementErr.Ready > 0:
+		return exitPartial
+	case errors.As(err, &timeoutErr):
+		return exitTimeout
+	}
+
+	return exitFailed
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	waitfor "github.com/dnnrly/wait-for"
+)
+
+// headerFlags collects HTTP headers given on the command line as "Name: value"
+type headerFlags map[string]string
+
+func (h headerFlags) String() string {
+	var headers []string
+	for name, value := range h {
+		headers = append(headers, name+": "+value)
+	}
+	sort.Strings(headers)
+	return strings.Join(headers, ",")
+}
+
+func (h headerFlags) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	name := strings.TrimSpace(parts[0])
+	if len(parts) != 2 || name == "" {
+		return fmt.Errorf("header must be \"Name: value\", not %s", value)
+	}
+
+	h[name] = strings.TrimSpace(parts[1])
+	return nil
+}
+
+// valueFlags collects settings given on the command line as name=value
+type valueFlags map[string]string
+
+func (v valueFlags) String() string {
+	var values []string
+	for name, value := range v {
+		values = append(values, name+"="+value)
+	}
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
+
+func (v valueFlags) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("must be name=value, not %s", value)
+	}
+
+	v[parts[0]] = parts[1]
+	return nil
+}
+
+// headerExpectationFlags collects the headers that HTTP responses must have, given on the
+// command line as Name to be present, Name=value to be equal or Name~regex to match
+type headerExpectationFlags []waitfor.HeaderExpectation
+
+func (h *headerExpectationFlags) String() string {
+	var expectations []string
+	for _, e := range *h {
+		switch {
+		case e.Equals != "":
+			expectations = append(expectations, e.Name+"="+e.Equals)
+		case e.Matches != "":
+			expectations = append(expectations, e.Name+"~"+e.Matches)
+		default:
+			expectations = append(expectations, e.Name)
+		}
+	}
+	return strings.Join(expectations, ",")
+}
+
+func (h *headerExpectationFlags) Set(value string) error {
+	expect := waitfor.HeaderExpectation{Name: value}
+	if i := strings.IndexAny(value, "=~"); i >= 0 {
+		expect.Name = value[:i]
+		if value[i] == '=' {
+			expect.Equals = value[i+1:]
+		} else {
+			expect.Matches = value[i+1:]
+		}
+	}
+	if expect.Name == "" {
+		return fmt.Errorf("header must be Name, Name=value or Name~regex, not %s", value)
+	}
+
+	*h = append(*h, expect)
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+)
+
+// Formats that can be used with -log-format
+const (
+	logFormatText   = "text"
+	logFormatJSON   = "json"
+	logFormatLogfmt = "logfmt"
+)
+
+// newLogger creates a logger that writes to w in format, leaving out anything below level
+func newLogger(format, level string, w io.Writer) (*slog.Logger, error) {
+	var minLevel slog.Level
+	if err := minLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %s, must be debug, info, warn or error", level)
+	}
+	options := &slog.HandlerOptions{Level: minLevel}
+
+	switch format {
+	case logFormatText:
+		return slog.New(&textHandler{
+			level: minLevel,
+			log:   log.New(w, "", log.LstdFlags|log.Lmicroseconds),
+		}), nil
+	case logFormatJSON:
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	case logFormatLogfmt:
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	}
+
+	return nil, fmt.Errorf("unknown log format %s, must be %s, %s or %s", format, logFormatText, logFormatJSON, logFormatLogfmt)
+}
+
+// textHandler writes just the message from each record on its own line with a timestamp,
+// which is easier to read than logfmt
+type textHandler struct {
+	level slog.Level
+	log   *log.Logger
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	h.log.Print(r.Message)
+	return nil
+}
+
+func (h *textHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *textHandler) WithGroup(string) slog.Handler {
+	return h
+}
@@ -1,58 +1,305 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
 	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	waitfor "github.com/dnnrly/wait-for"
 	"github.com/spf13/afero"
 )
 
 func main() {
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
-
 	timeoutParam := "5s"
 	httpTimeoutParam := "1s"
 	configFile := ""
 	var quiet bool
 	statusPatternParam := "^2..$"
+	interval := waitfor.DefaultInterval
+	backoff := waitfor.DefaultBackoff
+	maxInterval := waitfor.DefaultMaxInterval
+	var attemptTimeout time.Duration
+	var globalTimeout time.Duration
+	successThreshold := waitfor.DefaultSuccessThreshold
+	var stableFor time.Duration
+	var down bool
+	var anyTarget bool
+	var quorum int
+	var runOnTimeout bool
+	var exportEnv bool
+	outputFormat := outputText
+	logFormat := logFormatText
+	logLevel := "info"
+	var reports reportFlags
+	var noProgress bool
+	listen := ":9090"
+	httpMethod := waitfor.DefaultHTTPMethod
+	httpHeaders := headerFlags{}
+	var httpBody string
+	var expectBody string
+	var expectBodyRegex string
+	expectJSON := valueFlags{}
+	var expectHeaders headerExpectationFlags
+	var tlsConfig waitfor.TLSConfig
+	var auth waitfor.HTTPAuth
 
 	flag.StringVar(&timeoutParam, "timeout", timeoutParam, "time to wait for services to become available")
 	flag.StringVar(&httpTimeoutParam, "http_timeout", httpTimeoutParam, "timeout for requests made by a http client")
 	flag.StringVar(&configFile, "config", "", "configuration file to use")
 	flag.BoolVar(&quiet, "quiet", false, "reduce output to the minimum")
 	flag.StringVar(&statusPatternParam, "status", statusPatternParam, "A golang regex that represents the desired HTTP status response code")
-	flag.Parse()
+	flag.StringVar(&httpMethod, "http-method", httpMethod, "method to use for HTTP requests, such as GET, HEAD or POST")
+	flag.Var(httpHeaders, "http-header", "header to add to HTTP requests as \"Name: value\", can be repeated")
+	flag.StringVar(&httpBody, "http-body", "", "body to send with HTTP requests, use @path to read it from a file")
+	flag.StringVar(&expectBody, "expect-body", "", "text that the body of HTTP responses must contain")
+	flag.StringVar(&expectBodyRegex, "expect-body-regex", "", "A golang regex that the body of HTTP responses must match")
+	flag.Var(expectJSON, "expect-json", "value that HTTP responses must have at a JSON path as path=value, such as checks.0.status=ok, can be repeated")
+	flag.Var(&expectHeaders, "expect-header", "header that HTTP responses must have as Name, Name=value or Name~regex, can be repeated")
+	flag.StringVar(&auth.Username, "auth-user", "", "username to send with HTTP requests using basic auth")
+	flag.StringVar(&auth.PasswordEnv, "auth-password-env", "", "environment variable holding the password for -auth-user")
+	flag.StringVar(&auth.PasswordFile, "auth-password-file", "", "file holding the password for -auth-user")
+	flag.StringVar(&auth.TokenEnv, "auth-token-env", "", "environment variable holding a bearer token to send with HTTP requests")
+	flag.StringVar(&auth.TokenFile, "auth-token-file", "", "file holding a bearer token to send with HTTP requests, read before each attempt")
+	flag.StringVar(&tlsConfig.CAFile, "tls-ca", "", "PEM file of the certificate authorities to trust for TLS connections")
+	flag.StringVar(&tlsConfig.CertFile, "tls-cert", "", "PEM file of the client certificate to use for TLS connections")
+	flag.StringVar(&tlsConfig.KeyFile, "tls-key", "", "PEM file of the private key for -tls-cert")
+	flag.StringVar(&tlsConfig.ServerName, "tls-server-name", "", "name expected in the certificate of services connected to with TLS")
+	flag.StringVar(&tlsConfig.MinVersion, "tls-min-version", "", "oldest version of TLS allowed: 1.0, 1.1, 1.2 or 1.3")
+	flag.BoolVar(&tlsConfig.InsecureSkipVerify, "insecure-skip-verify", false, "accept any certificate from services connected to with TLS")
+	flag.DurationVar(&interval, "interval", interval, "time to pause between attempts to reach a service")
+	flag.StringVar(&backoff, "backoff", backoff, "how the pause between attempts changes: constant, linear, exponential or jitter")
+	flag.DurationVar(&maxInterval, "max-interval", maxInterval, "longest pause between attempts when using a backoff")
+	flag.DurationVar(&attemptTimeout, "attempt-timeout", attemptTimeout, "time limit for each attempt to reach a service, http_timeout overrides this for HTTP")
+	flag.DurationVar(&globalTimeout, "global-timeout", globalTimeout, "time limit for all services to become available together")
+	flag.IntVar(&successThreshold, "success-threshold", successThreshold, "number of consecutive successful attempts before a service is available")
+	flag.DurationVar(&stableFor, "stable-for", stableFor, "time that a service must keep succeeding before it is available")
+	flag.BoolVar(&down, "down", false, "wait for services to become unavailable instead")
+	flag.BoolVar(&anyTarget, "any", false, "only wait for one of the services to become available")
+	flag.IntVar(&quorum, "quorum", 0, "only wait for this number of the services to become available")
+	flag.BoolVar(&runOnTimeout, "run-on-timeout", false, "run the command after -- even if the services timed out")
+	flag.BoolVar(&exportEnv, "env", false, "pass the status of each service to the command after -- in environment variables")
+	flag.StringVar(&outputFormat, "output", outputFormat, "how to write out progress: text, json or ndjson to stream each record as it happens")
+	flag.StringVar(&logFormat, "log-format", logFormat, "how to write log messages: text, json or logfmt")
+	flag.StringVar(&logLevel, "log-level", logLevel, "the least important log messages to write: debug, info, warn or error")
+	flag.BoolVar(&noProgress, "no-progress", false, "write log messages instead of a live table of progress when writing to a terminal")
+	flag.StringVar(&listen, "listen", listen, "address to serve on in watch and serve modes")
+	flag.Var(&reports, "report", "write a report to a file as format=path, where format is junit, json or markdown, can be repeated")
+	mode, args := splitMode(os.Args[1:])
+	args, command := splitCommand(args)
+	_ = flag.CommandLine.Parse(args)
 
 	fs := afero.NewOsFs()
 
-	logger := func(f string, a ...interface{}) {
-		log.Printf(f, a...)
+	slogger, err := newLogger(logFormat, logLevel, os.Stderr)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%v", err)
+		os.Exit(exitUsage)
 	}
+	var verbose waitfor.StructuredLogger = slogger
+	logger := verbose
 
 	if quiet {
 		logger = waitfor.NullLogger
 	}
 
-	config, err := waitfor.OpenConfig(configFile, timeoutParam, httpTimeoutParam, statusPatternParam, fs)
+	out, err := newOutput(outputFormat, os.Stdout)
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "%v", err)
-		os.Exit(1)
+		os.Exit(exitUsage)
+	}
+	var handlers []waitfor.EventHandler
+	if out != nil {
+		logger = waitfor.NullLogger
+		handlers = append(handlers, out.event)
+	}
+
+	var display *liveDisplay
+	if mode == "" && out == nil && !quiet && !noProgress && logFormat == logFormatText && isTerminal(os.Stdout) {
+		display = newLiveDisplay(os.Stdout)
+		logger = waitfor.NullLogger
+		verbose = waitfor.NullLogger
+		handlers = append(handlers, display.event)
+	}
+
+	// Defaults given on the command line take the place of the ones in the config file so
+	// that they apply to the targets in it as well
+	overrides := func(config *waitfor.Config) {
+		if isFlagSet("interval") {
+			config.DefaultInterval = interval
+		}
+		if isFlagSet("backoff") {
+			config.DefaultBackoff = backoff
+		}
+		if isFlagSet("max-interval") {
+			config.DefaultMaxInterval = maxInterval
+		}
+		if isFlagSet("attempt-timeout") {
+			config.DefaultAttemptTimeout = attemptTimeout
+		}
+		if isFlagSet("success-threshold") {
+			config.DefaultSuccessThreshold = successThreshold
+		}
+		if isFlagSet("stable-for") {
+			config.DefaultStableFor = stableFor
+		}
+		if down {
+			config.DefaultMode = waitfor.ModeDown
+		}
+	}
+
+	config, err := waitfor.OpenConfig(configFile, timeoutParam, httpTimeoutParam, statusPatternParam, fs, overrides)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%v", err)
+		os.Exit(exitCode(err))
+	}
+	if isFlagSet("http-method") {
+		config.DefaultHTTPMethod = httpMethod
+	}
+	if len(httpHeaders) > 0 {
+		config.DefaultHTTPHeaders = httpHeaders
+	}
+	if isFlagSet("http-body") {
+		config.DefaultHTTPBody = httpBody
+	}
+	if expectBody != "" || expectBodyRegex != "" || len(expectJSON) > 0 {
+		config.DefaultHTTPExpectBody = &waitfor.BodyExpectation{
+			Contains: expectBody,
+			Matches:  expectBodyRegex,
+			JSON:     expectJSON,
+		}
+	}
+	if len(expectHeaders) > 0 {
+		config.DefaultHTTPExpectHeaders = expectHeaders
+	}
+	if auth != (waitfor.HTTPAuth{}) {
+		config.DefaultAuth = &auth
+	}
+	if tlsConfig != (waitfor.TLSConfig{}) {
+		config.DefaultTLS = &tlsConfig
+	}
+	if anyTarget && quorum > 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "-any and -quorum can't be used together")
+		os.Exit(exitUsage)
+	}
+	if anyTarget {
+		config.Require = waitfor.RequireAny
+	}
+	if quorum > 0 {
+		config.Require = strconv.Itoa(quorum)
+	}
+	if attemptTimeout > 0 && !isFlagSet("http_timeout") {
+		config.DefaultHTTPClientTimeout = attemptTimeout
+	}
+	if isFlagSet("global-timeout") {
+		config.GlobalTimeout = globalTimeout
+	}
+
+	if exportEnv && len(command) > 0 {
+		if err := checkTargetEnv(config, flag.Args()); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%v", err)
+			os.Exit(exitUsage)
+		}
 	}
 
 	waitfor.SupportedWaiters = map[string]waitfor.Waiter{
-		"http": waitfor.WaiterFunc(waitfor.HTTPWaiter),
-		"tcp":  waitfor.WaiterFunc(waitfor.TCPWaiter),
-		"grpc": waitfor.WaiterFunc(waitfor.GRPCWaiter),
-		"dns":  waitfor.NewDNSWaiter(net.LookupIP, logger),
+		"http": waitfor.ContextWaiterFunc(waitfor.HTTPWaiterContext),
+		"tcp":  waitfor.ContextWaiterFunc(waitfor.TCPWaiterContext),
+		"grpc": waitfor.ContextWaiterFunc(waitfor.GRPCWaiterContext),
+		"dns":  waitfor.NewStructuredDNSWaiter(net.LookupIP, logger),
+	}
+
+	if mode != "" {
+		ctx, stop := interruptContext(verbose)
+		if mode == modeServe {
+			err = runServe(ctx, config, flag.Args(), logger, listen)
+		} else {
+			err = runWatch(ctx, config, flag.Args(), logger, listen)
+		}
+		stop()
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%v", err)
+			os.Exit(exitCode(err))
+		}
+		return
+	}
+
+	if display != nil {
+		display.start()
 	}
+	ctx, stop := interruptContext(verbose)
+	results, err := waitfor.WaitOnResults(ctx, config, logger, flag.Args(), waitfor.SupportedWaiters, handlers...)
+	stop()
 
-	err = waitfor.WaitOn(config, logger, flag.Args(), waitfor.SupportedWaiters)
+	if display != nil {
+		display.finish(results)
+	} else if out != nil {
+		if outErr := out.finish(results, err); outErr != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "unable to write output: %v\n", outErr)
+		}
+	} else if errors.Is(err, context.Canceled) {
+		// Always say how far we got when interrupted, even if asked to be quiet
+		logResults(verbose, results)
+	} else {
+		logResults(logger, results)
+	}
+	if reportErr := reports.write(results, err); reportErr != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%v\n", reportErr)
+	}
+	if len(command) > 0 && shouldRunCommand(err, runOnTimeout) {
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+
+		env := os.Environ()
+		if exportEnv {
+			env = append(env, targetEnv(results)...)
+		}
+
+		logger.Info(fmt.Sprintf("running %s", strings.Join(command, " ")), "command", command)
+		err = runCommand(command, env)
+		_, _ = fmt.Fprintf(os.Stderr, "unable to run %s: %v", command[0], err)
+		os.Exit(exitCannotRun)
+	}
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "%v", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
+
+// logResults summarises how waiting went for each of the targets
+func logResults(logger waitfor.StructuredLogger, results []waitfor.Result) {
+	for _, r := range results {
+		fields := []interface{}{
+			"target", r.Name,
+			"type", r.Type,
+			"status", r.Status,
+			"attempts", r.Attempts,
+			"elapsed", r.Duration,
+		}
+		switch {
+		case r.Status == waitfor.StatusReady:
+			logger.Info(fmt.Sprintf("%s: %s after %d attempts in %s", r.Name, r.Status, r.Attempts, r.Duration.Round(time.Millisecond)), fields...)
+		case r.LastError != nil:
+			logger.Warn(fmt.Sprintf("%s: %s after %d attempts in %s, last error: %v", r.Name, r.Status, r.Attempts, r.Duration.Round(time.Millisecond), r.LastError), append(fields, "error", r.LastError)...)
+		default:
+			logger.Warn(fmt.Sprintf("%s: %s after %d attempts", r.Name, r.Status, r.Attempts), fields...)
+		}
+	}
+}
+
+// isFlagSet returns true if the flag was given on the command line
+func isFlagSet(name string) bool {
+	found := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	waitfor "github.com/dnnrly/wait-for"
+)
+
+// probeDurationBuckets are the upper bounds, in seconds, of the probe duration histogram
+var probeDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogram counts observations in to buckets in the same way as a Prometheus histogram
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(probeDurationBuckets))
+	}
+	for i, bound := range probeDurationBuckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// metrics serves the state of watched targets in the Prometheus text format
+type metrics struct {
+	lock      sync.Mutex
+	watcher   *waitfor.Watcher
+	durations map[string]*histogram
+	successes map[string]uint64
+	failures  map[string]uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		durations: map[string]*histogram{},
+		successes: map[string]uint64{},
+		failures:  map[string]uint64{},
+	}
+}
+
+// event records each probe, it is used as a waitfor.EventHandler
+func (m *metrics) event(e waitfor.Event) {
+	if e.Type != waitfor.EventAttempt {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	h, found := m.durations[e.Name]
+	if !found {
+		h = &histogram{}
+		m.durations[e.Name] = h
+	}
+	h.observe(e.Duration.Seconds())
+
+	if e.Err == nil {
+		m.successes[e.Name]++
+	} else {
+		m.failures[e.Name]++
+	}
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.write(w)
+}
+
+func (m *metrics) write(w io.Writer) {
+	states := m.watcher.States()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var b strings.Builder
+
+	writeHeader(&b, "wait_for_target_up", "gauge", "Whether the target is ready, 1 if it is and 0 if it isn't.")
+	for _, s := range states {
+		value := 0
+		if s.Ready {
+			value = 1
+		}
+		fmt.Fprintf(&b, "wait_for_target_up{%s} %d\n", targetLabels(s), value)
+	}
+
+	writeHeader(&b, "wait_for_target_consecutive_failures", "gauge", "Number of probes in a row that have failed.")
+	for _, s := range states {
+		fmt.Fprintf(&b, "wait_for_target_consecutive_failures{%s} %d\n", targetLabels(s), s.ConsecutiveFailures)
+	}
+
+	writeHeader(&b, "wait_for_target_last_success_timestamp_seconds", "gauge", "When a probe of the target last succeeded, as a Unix timestamp.")
+	for _, s := range states {
+		if s.LastSuccess.IsZero() {
+			continue
+		}
+		fmt.Fprintf(&b, "wait_for_target_last_success_timestamp_seconds{%s} %.3f\n", targetLabels(s), float64(s.LastSuccess.UnixNano())/1e9)
+	}
+
+	writeHeader(&b, "wait_for_probes_total", "counter", "Number of times the target has been probed.")
+	for _, s := range states {
+		fmt.Fprintf(&b, "wait_for_probes_total{%s,result=\"success\"} %d\n", targetLabels(s), m.successes[s.Name])
+		fmt.Fprintf(&b, "wait_for_probes_total{%s,result=\"failure\"} %d\n", targetLabels(s), m.failures[s.Name])
+	}
+
+	writeHeader(&b, "wait_for_probe_duration_seconds", "histogram", "How long each probe of the target took.")
+	for _, s := range states {
+		h, found := m.durations[s.Name]
+		if !found {
+			continue
+		}
+		labels := targetLabels(s)
+		for i, bound := range probeDurationBuckets {
+			fmt.Fprintf(&b, "wait_for_probe_duration_seconds_bucket{%s,le=\"%g\"} %d\n", labels, bound, h.counts[i])
+		}
+		fmt.Fprintf(&b, "wait_for_probe_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(&b, "wait_for_probe_duration_seconds_sum{%s} %g\n", labels, h.sum)
+		fmt.Fprintf(&b, "wait_for_probe_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+
+	_, _ = io.WriteString(w, b.String())
+}
+
+func writeHeader(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func targetLabels(s waitfor.TargetState) string {
+	return fmt.Sprintf("target=\"%s\",type=\"%s\"", labelValue(s.Name), labelValue(s.Type))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labelValue(v string) string {
+	return labelEscaper.Replace(v)
+}
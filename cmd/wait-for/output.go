@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	waitfor "github.com/dnnrly/wait-for"
+)
+
+// Formats that can be used with -output
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+)
+
+// eventRecord is how an attempt or change of status is written as JSON
+type eventRecord struct {
+	Record     string    `json:"record"`
+	Time       time.Time `json:"time"`
+	Name       string    `json:"name"`
+	Attempt    int       `json:"attempt,omitempty"`
+	DurationMS int64     `json:"duration_ms,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// resultRecord is how the result for a target is written as JSON
+type resultRecord struct {
+	Record       string     `json:"record"`
+	Name         string     `json:"name"`
+	Type         string     `json:"type"`
+	Target       string     `json:"target"`
+	Status       string     `json:"status"`
+	Attempts     int        `json:"attempts"`
+	DurationMS   int64      `json:"duration_ms"`
+	Error        string     `json:"error,omitempty"`
+	FirstSuccess *time.Time `json:"first_success,omitempty"`
+}
+
+// jsonOutput writes events and results as JSON. When streaming, each record is written
+// on its own line as soon as it happens, otherwise everything is written in one document
+// once waiting has finished.
+type jsonOutput struct {
+	lock   sync.Mutex
+	w      io.Writer
+	stream bool
+	events []eventRecord
+}
+
+// newOutput creates the output for format, or nil for plain text
+func newOutput(format string, w io.Writer) (*jsonOutput, error) {
+	switch format {
+	case outputText:
+		return nil, nil
+	case outputJSON:
+		return &jsonOutput{w: w}, nil
+	case outputNDJSON:
+		return &jsonOutput{w: w, stream: true}, nil
+	}
+	return nil, fmt.Errorf("unknown output %s, must be %s, %s or %s", format, outputText, outputJSON, outputNDJSON)
+}
+
+// event records e, it is used as a waitfor.EventHandler
+func (o *jsonOutput) event(e waitfor.Event) {
+	record := eventRecord{
+		Record:     string(e.Type),
+		Time:       e.Time,
+		Name:       e.Name,
+		Attempt:    e.Attempt,
+		DurationMS: e.Duration.Milliseconds(),
+		Status:     string(e.Status),
+		Error:      errorString(e.Err),
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.stream {
+		_ = json.NewEncoder(o.w).Encode(record)
+		return
+	}
+	o.events = append(o.events, record)
+}
+
+// finish writes the result for each target along with anything that was waiting to be written
+func (o *jsonOutput) finish(results []waitfor.Result, err error) error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	records := newResultRecords(results)
+	if o.stream {
+		enc := json.NewEncoder(o.w)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	events := o.events
+	if events == nil {
+		events = []eventRecord{}
+	}
+	enc := json.NewEncoder(o.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Events  []eventRecord  `json:"events"`
+		Results []resultRecord `json:"results"`
+		Error   string         `json:"error,omitempty"`
+	}{
+		Events:  events,
+		Results: records,
+		Error:   errorString(err),
+	})
+}
+
+func newResultRecords(results []waitfor.Result) []resultRecord {
+	records := []resultRecord{}
+	for _, r := range results {
+		record := resultRecord{
+			Record:     "result",
+			Name:       r.Name,
+			Type:       r.Type,
+			Target:     r.Target,
+			Status:     string(r.Status),
+			Attempts:   r.Attempts,
+			DurationMS: r.Duration.Milliseconds(),
+			Error:      errorString(r.LastError),
+		}
+		if !r.FirstSuccess.IsZero() {
+			firstSuccess := r.FirstSuccess
+			record.FirstSuccess = &firstSuccess
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
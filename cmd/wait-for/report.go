@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	waitfor "github.com/dnnrly/wait-for"
+)
+
+// reportWriter writes a report of the results of waiting
+type reportWriter func(w io.Writer, results []waitfor.Result, err error) error
+
+// supportedReports maps the report formats that can be used with -report to their writers
+var supportedReports = map[string]reportWriter{
+	"junit":    writeJUnitReport,
+	"json":     writeJSONReport,
+	"markdown": writeMarkdownReport,
+}
+
+// report is a report to write and where to write it
+type report struct {
+	format string
+	path   string
+}
+
+// reportFlags collects the reports asked for on the command line as format=path
+type reportFlags []report
+
+func (r *reportFlags) String() string {
+	var reports []string
+	for _, rep := range *r {
+		reports = append(reports, rep.format+"="+rep.path)
+	}
+	return strings.Join(reports, ",")
+}
+
+func (r *reportFlags) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return fmt.Errorf("report must be format=path, not %s", value)
+	}
+	if _, found := supportedReports[parts[0]]; !found {
+		return fmt.Errorf("unknown report format %s, must be junit, json or markdown", parts[0])
+	}
+
+	*r = append(*r, report{format: parts[0], path: parts[1]})
+	return nil
+}
+
+// write creates each of the reports
+func (r reportFlags) write(results []waitfor.Result, err error) error {
+	for _, rep := range r {
+		if writeErr := rep.write(results, err); writeErr != nil {
+			return fmt.Errorf("unable to write %s report to %s: %v", rep.format, rep.path, writeErr)
+		}
+	}
+	return nil
+}
+
+func (r report) write(results []waitfor.Result, err error) error {
+	f, createErr := os.Create(r.path)
+	if createErr != nil {
+		return createErr
+	}
+
+	writeErr := supportedReports[r.format](f, results, err)
+	closeErr := f.Close()
+	if writeErr != nil {
+		return writeErr
+	}
+	return closeErr
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	ClassName  string          `xml:"classname,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Failure    *junitMessage   `xml:"failure"`
+	Skipped    *junitMessage   `xml:"skipped"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnitReport writes a JUnit XML report with a test case for each target, so that
+// targets that weren't ready show up as failures in CI
+func writeJUnitReport(w io.Writer, results []waitfor.Result, err error) error {
+	suite := junitTestSuite{
+		Name:      "wait-for",
+		Timestamp: time.Now().Format("2006-01-02T15:04:05"),
+	}
+
+	var total time.Duration
+	for _, r := range results {
+		tc := junitTestCase{
+			Name:      r.Name,
+			ClassName: "wait-for." + r.Type,
+			Time:      seconds(r.Duration),
+			Properties: []junitProperty{
+				{Name: "target", Value: r.Target},
+				{Name: "status", Value: string(r.Status)},
+				{Name: "attempts", Value: fmt.Sprint(r.Attempts)},
+			},
+		}
+
+		switch r.Status {
+		case waitfor.StatusFailed:
+			suite.Failures++
+			tc.Failure = &junitMessage{
+				Message: fmt.Sprintf("%s failed after %d attempts", r.Name, r.Attempts),
+				Type:    string(r.Status),
+				Text:    errorString(r.LastError),
+			}
+		case waitfor.StatusCancelled:
+			// Targets are also stopped once enough of the others are ready, which isn't a failure
+			if err == nil {
+				suite.Skipped++
+				tc.Skipped = &junitMessage{
+					Message: fmt.Sprintf("stopped waiting for %s after %d attempts as enough targets were ready", r.Name, r.Attempts),
+				}
+				break
+			}
+			suite.Failures++
+			tc.Failure = &junitMessage{
+				Message: fmt.Sprintf("stopped waiting for %s after %d attempts: %v", r.Name, r.Attempts, err),
+				Type:    string(r.Status),
+				Text:    errorString(r.LastError),
+			}
+		}
+
+		if r.Duration > total {
+			total = r.Duration
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+	suite.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, writeErr := io.WriteString(w, "\n")
+	return writeErr
+}
+
+// writeJSONReport writes the result for each target as JSON, in the same form as -output json
+func writeJSONReport(w io.Writer, results []waitfor.Result, err error) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Results []resultRecord `json:"results"`
+		Error   string         `json:"error,omitempty"`
+	}{
+		Results: newResultRecords(results),
+		Error:   errorString(err),
+	})
+}
+
+// writeMarkdownReport writes a table with a row for each target, suitable for a CI job summary
+func writeMarkdownReport(w io.Writer, results []waitfor.Result, err error) error {
+	var b strings.Builder
+
+	b.WriteString("| Target | Type | Status | Attempts | Duration | Last error |\n")
+	b.WriteString("|--------|------|--------|----------|----------|------------|\n")
+	for _, r := range results {
+		fmt.Fprintf(&b, "| %s | %s | %s | %d | %s | %s |\n",
+			markdownCell(r.Name),
+			markdownCell(r.Type),
+			r.Status,
+			r.Attempts,
+			r.Duration.Round(time.Millisecond),
+			markdownCell(errorString(r.LastError)),
+		)
+	}
+	if err != nil {
+		fmt.Fprintf(&b, "\n**Error:** %s\n", markdownCell(err.Error()))
+	}
+
+	_, writeErr := io.WriteString(w, b.String())
+	return writeErr
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	waitfor "github.com/dnnrly/wait-for"
+)
+
+func TestWriteJUnitReport_cancelledTargetsFailWithTheReason(t *testing.T) {
+	var b bytes.Buffer
+	err := writeJUnitReport(&b, []waitfor.Result{
+		{Name: "api", Type: "http", Status: waitfor.StatusReady, Attempts: 1, Duration: time.Second},
+		{Name: "db", Type: "tcp", Status: waitfor.StatusFailed, Attempts: 3, LastError: errors.New("connection refused")},
+		{Name: "cache", Type: "tcp", Status: waitfor.StatusCancelled, Attempts: 2, LastError: errors.New("no route to host")},
+	}, errors.New("timed out waiting for db: connection refused"))
+	require.NoError(t, err)
+
+	report := b.String()
+	assert.Contains(t, report, `<testsuite name="wait-for" tests="3" failures="2" skipped="0"`)
+	assert.Contains(t, report, `<failure message="db failed after 3 attempts" type="failed">connection refused</failure>`)
+	assert.Contains(t, report, `<failure message="stopped waiting for cache after 2 attempts: timed out waiting for db: connection refused" type="cancelled">no route to host</failure>`)
+	assert.NotContains(t, report, "<skipped")
+}
+
+func TestWriteJUnitReport_targetsNotNeededAreSkipped(t *testing.T) {
+	var b bytes.Buffer
+	err := writeJUnitReport(&b, []waitfor.Result{
+		{Name: "api", Type: "http", Status: waitfor.StatusReady, Attempts: 1},
+		{Name: "replica", Type: "http", Status: waitfor.StatusCancelled, Attempts: 1},
+	}, nil)
+	require.NoError(t, err)
+
+	report := b.String()
+	assert.Contains(t, report, `<testsuite name="wait-for" tests="2" failures="0" skipped="1"`)
+	assert.Contains(t, report, `<skipped message="stopped waiting for replica after 1 attempts as enough targets were ready"></skipped>`)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	waitfor "github.com/dnnrly/wait-for"
+)
+
+// stateRecord is how the state of a target is written in the status response
+type stateRecord struct {
+	Name                 string     `json:"name"`
+	Type                 string     `json:"type"`
+	Target               string     `json:"target"`
+	Ready                bool       `json:"ready"`
+	Probes               int        `json:"probes"`
+	ConsecutiveSuccesses int        `json:"consecutive_successes"`
+	ConsecutiveFailures  int        `json:"consecutive_failures"`
+	LastProbe            *time.Time `json:"last_probe,omitempty"`
+	LastDurationMS       int64      `json:"last_duration_ms"`
+	LastSuccess          *time.Time `json:"last_success,omitempty"`
+	Error                string     `json:"error,omitempty"`
+}
+
+// runServe keeps probing the targets and serves whether they are ready on listen until
+// ctx is cancelled
+func runServe(ctx context.Context, config *waitfor.Config, targets []string, logger waitfor.StructuredLogger, listen string) error {
+	watcher, err := waitfor.NewWatcher(config, targets, waitfor.SupportedWaiters, logger)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		if watcher.Ready() {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, watcher)
+	})
+
+	return serveWhileWatching(ctx, watcher, listen, mux, logger)
+}
+
+// writeStatus writes the state of each target as JSON, with the same status code as /ready
+func writeStatus(w http.ResponseWriter, watcher *waitfor.Watcher) {
+	ready := watcher.Ready()
+
+	records := []stateRecord{}
+	for _, s := range watcher.States() {
+		records = append(records, newStateRecord(s))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if ready {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(struct {
+		Ready   bool          `json:"ready"`
+		Targets []stateRecord `json:"targets"`
+	}{
+		Ready:   ready,
+		Targets: records,
+	})
+}
+
+func newStateRecord(s waitfor.TargetState) stateRecord {
+	record := stateRecord{
+		Name:                 s.Name,
+		Type:                 s.Type,
+		Target:               s.Target,
+		Ready:                s.Ready,
+		Probes:               s.Probes,
+		ConsecutiveSuccesses: s.ConsecutiveSuccesses,
+		ConsecutiveFailures:  s.ConsecutiveFailures,
+		LastDurationMS:       s.LastDuration.Milliseconds(),
+		Error:                errorString(s.LastError),
+	}
+	if !s.LastProbe.IsZero() {
+		lastProbe := s.LastProbe
+		record.LastProbe = &lastProbe
+	}
+	if !s.LastSuccess.IsZero() {
+		lastSuccess := s.LastSuccess
+		record.LastSuccess = &lastSuccess
+	}
+	return record
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	waitfor "github.com/dnnrly/wait-for"
+)
+
+// interruptContext creates a context that is cancelled when wait-for is interrupted or
+// terminated, so that waiting stops cleanly. Call stop to go back to the default handling
+// of signals.
+func interruptContext(logger waitfor.StructuredLogger) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			logger.Warn(fmt.Sprintf("received %v, stopping", sig), "signal", sig.String())
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	waitfor "github.com/dnnrly/wait-for"
+)
+
+// Subcommands that keep running instead of exiting once the targets are ready
+const (
+	modeWatch = "watch"
+	modeServe = "serve"
+)
+
+// splitMode separates the subcommand, if there is one, from the rest of the arguments
+func splitMode(args []string) (string, []string) {
+	if len(args) > 0 && (args[0] == modeWatch || args[0] == modeServe) {
+		return args[0], args[1:]
+	}
+	return "", args
+}
+
+// runWatch keeps probing the targets and serves metrics describing them on listen until
+// ctx is cancelled
+func runWatch(ctx context.Context, config *waitfor.Config, targets []string, logger waitfor.StructuredLogger, listen string) error {
+	m := newMetrics()
+	watcher, err := waitfor.NewWatcher(config, targets, waitfor.SupportedWaiters, logger, m.event)
+	if err != nil {
+		return err
+	}
+	m.watcher = watcher
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+
+	return serveWhileWatching(ctx, watcher, listen, mux, logger)
+}
+
+// serveWhileWatching runs watcher and serves handler on listen until ctx is cancelled
+func serveWhileWatching(ctx context.Context, watcher *waitfor.Watcher, listen string, handler http.Handler, logger waitfor.StructuredLogger) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info(fmt.Sprintf("listening on %s", listen), "address", listen)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	watched := make(chan struct{})
+	go func() {
+		watcher.Run(ctx)
+		close(watched)
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-serveErr:
+		cancel()
+	}
+	<-watched
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	_ = server.Shutdown(shutdownCtx)
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("unable to serve on %s: %w", listen, err)
+	}
+	return nil
+}
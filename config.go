@@ -1,8 +1,10 @@
 package waitfor
 
 import (
-	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
@@ -18,6 +20,30 @@ const DefaultHTTPClientTimeout = time.Second
 // DefaultStatusPattern is a default value for the Regex pattern to match in the expected result
 const DefaultStatusPattern = "^2..$"
 
+// DefaultHTTPMethod is the method used for HTTP requests when a target doesn't specify one
+const DefaultHTTPMethod = http.MethodGet
+
+// DefaultInterval is the amount of time to pause between attempts to reach a target
+const DefaultInterval = time.Second
+
+// DefaultBackoff is the name of the strategy used to change the interval between attempts
+const DefaultBackoff = "constant"
+
+// DefaultMaxInterval is the longest that a backoff strategy will pause between attempts
+const DefaultMaxInterval = time.Second * 30
+
+// ModeUp waits for a target to become available
+const ModeUp = "up"
+
+// ModeDown waits for a target to become unavailable
+const ModeDown = "down"
+
+// DefaultMode is the mode used for targets that don't specify one
+const DefaultMode = ModeUp
+
+// DefaultSuccessThreshold is the number of consecutive successful attempts needed for a target to be ready
+const DefaultSuccessThreshold = 1
+
 // TargetConfig is the configuration of a single target
 type TargetConfig struct {
 	// Type is the kind of target being described
@@ -26,18 +52,78 @@ type TargetConfig struct {
 	Target string
 	// Timeout is the timeout to use for this specific target if it is different to DefaultTimeout
 	Timeout time.Duration
-	// HTTPClientTimeout is the timeout for requests made by a http client
+	// Mode is either ModeUp to wait for the target to be available or ModeDown to wait for it to go away
+	Mode string
+	// AttemptTimeout is the longest that a single attempt to reach the target can take
+	AttemptTimeout time.Duration `yaml:"attempt-timeout"`
+	// HTTPClientTimeout is the timeout for requests made by a http client, overriding AttemptTimeout
 	HTTPClientTimeout time.Duration `yaml:"http-client-timeout"`
 	// Regex is the regular expression pattern to match in the expected http status code result
 	StatusPattern string `yaml:"http-client-status-pattern"`
+	// HTTPMethod is the method used for each HTTP request, such as GET, HEAD or POST
+	HTTPMethod string `yaml:"http-method"`
+	// HTTPHeaders are added to each HTTP request, setting Host changes the host that is sent
+	HTTPHeaders map[string]string `yaml:"http-headers"`
+	// HTTPBody is sent with each HTTP request, prefixing it with @ reads the body from that file
+	HTTPBody string `yaml:"http-body"`
+	// HTTPExpectBody describes what the body of the response must contain, as well as matching StatusPattern
+	HTTPExpectBody *BodyExpectation `yaml:"http-expect-body"`
+	// HTTPExpectHeaders lists the headers that the response must have, as well as matching StatusPattern
+	HTTPExpectHeaders []HeaderExpectation `yaml:"http-expect-headers"`
+	// Auth is how to authenticate HTTP requests to the target
+	Auth *HTTPAuth `yaml:"auth"`
+	// TLS is how to make TLS connections to the target
+	TLS *TLSConfig `yaml:"tls"`
+	// Interval is the pause between attempts to reach the target
+	Interval time.Duration
+	// Backoff is the name of the strategy used to change the interval after each failed attempt
+	Backoff string
+	// MaxInterval is the longest pause that the backoff strategy is allowed to use
+	MaxInterval time.Duration `yaml:"max-interval"`
+	// SuccessThreshold is the number of consecutive successful attempts needed before the target is ready
+	SuccessThreshold int `yaml:"success-threshold"`
+	// StableFor is how long the target must keep succeeding before it is ready
+	StableFor time.Duration `yaml:"stable-for"`
+	// DependsOn lists the targets that must be ready before this target is waited on
+	DependsOn []string `yaml:"depends-on"`
+
+	// compiled is true once the regular expressions that responses are checked against have
+	// been compiled, so that they aren't compiled again on every attempt
+	compiled      bool
+	statusPattern *regexp.Regexp
+}
+
+// GroupConfig is a named set of targets that is ready when enough of those targets are ready
+type GroupConfig struct {
+	// Targets lists the names of the targets in the group
+	Targets []string
+	// Require is RequireAll, RequireAny or the number of targets that must be ready
+	Require string
 }
 
 // Config represents all the config that can be defined in a config file
 type Config struct {
 	DefaultTimeout           time.Duration `yaml:"default-timeout"`
+	GlobalTimeout            time.Duration `yaml:"global-timeout"`
+	Require                  string        `yaml:"require"`
 	Targets                  map[string]TargetConfig
-	DefaultHTTPClientTimeout time.Duration `yaml:"default-http-client-timeout"`
-	DefaultStatusPattern     string        `yaml:"default-http-client-status-pattern"`
+	Groups                   map[string]GroupConfig
+	DefaultMode              string              `yaml:"default-mode"`
+	DefaultAttemptTimeout    time.Duration       `yaml:"default-attempt-timeout"`
+	DefaultHTTPClientTimeout time.Duration       `yaml:"default-http-client-timeout"`
+	DefaultStatusPattern     string              `yaml:"default-http-client-status-pattern"`
+	DefaultHTTPMethod        string              `yaml:"default-http-method"`
+	DefaultHTTPHeaders       map[string]string   `yaml:"default-http-headers"`
+	DefaultHTTPBody          string              `yaml:"default-http-body"`
+	DefaultHTTPExpectBody    *BodyExpectation    `yaml:"default-http-expect-body"`
+	DefaultHTTPExpectHeaders []HeaderExpectation `yaml:"default-http-expect-headers"`
+	DefaultAuth              *HTTPAuth           `yaml:"default-auth"`
+	DefaultTLS               *TLSConfig          `yaml:"default-tls"`
+	DefaultInterval          time.Duration       `yaml:"default-interval"`
+	DefaultBackoff           string              `yaml:"default-backoff"`
+	DefaultMaxInterval       time.Duration       `yaml:"default-max-interval"`
+	DefaultSuccessThreshold  int                 `yaml:"default-success-threshold"`
+	DefaultStableFor         time.Duration       `yaml:"default-stable-for"`
 }
 
 // NewConfig creates an empty Config
@@ -45,91 +131,295 @@ func NewConfig() *Config {
 	return &Config{
 		DefaultTimeout:           DefaultTimeout,
 		Targets:                  map[string]TargetConfig{},
+		DefaultMode:              DefaultMode,
 		DefaultHTTPClientTimeout: DefaultHTTPClientTimeout,
 		DefaultStatusPattern:     DefaultStatusPattern,
+		DefaultHTTPMethod:        DefaultHTTPMethod,
+		DefaultInterval:          DefaultInterval,
+		DefaultBackoff:           DefaultBackoff,
+		DefaultMaxInterval:       DefaultMaxInterval,
+		DefaultSuccessThreshold:  DefaultSuccessThreshold,
 	}
 }
 
-// NewConfigFromFile reads configuration from the file provided
-func NewConfigFromFile(r io.Reader) (*Config, error) {
+// ConfigOverride changes the settings read from a config file before its defaults are
+// applied to the targets, such as defaults that have been given on the command line
+type ConfigOverride func(*Config)
+
+// NewConfigFromFile reads configuration from the file provided, applying any overrides
+// before the targets are given their defaults
+func NewConfigFromFile(r io.Reader, overrides ...ConfigOverride) (*Config, error) {
 	config := Config{}
 	err := yaml.NewDecoder(r).Decode(&config)
 	if err != nil {
-		return nil, err
+		return nil, &ConfigError{Err: err}
+	}
+	if config.Targets == nil {
+		config.Targets = map[string]TargetConfig{}
+	}
+	for _, override := range overrides {
+		override(&config)
 	}
 	if config.DefaultTimeout == 0 {
 		config.DefaultTimeout = DefaultTimeout
 	}
+	if config.DefaultMode == "" {
+		config.DefaultMode = DefaultMode
+	}
+	if config.DefaultHTTPClientTimeout == 0 {
+		config.DefaultHTTPClientTimeout = config.DefaultAttemptTimeout
+	}
 	if config.DefaultHTTPClientTimeout == 0 {
 		config.DefaultHTTPClientTimeout = DefaultHTTPClientTimeout
 	}
 	if config.DefaultStatusPattern == "" {
 		config.DefaultStatusPattern = DefaultStatusPattern
 	}
+	if config.DefaultHTTPMethod == "" {
+		config.DefaultHTTPMethod = DefaultHTTPMethod
+	}
+	if config.DefaultInterval == 0 {
+		config.DefaultInterval = DefaultInterval
+	}
+	if config.DefaultBackoff == "" {
+		config.DefaultBackoff = DefaultBackoff
+	}
+	if config.DefaultMaxInterval == 0 {
+		config.DefaultMaxInterval = DefaultMaxInterval
+	}
+	if config.DefaultSuccessThreshold == 0 {
+		config.DefaultSuccessThreshold = DefaultSuccessThreshold
+	}
 	for t := range config.Targets {
 		target := config.Targets[t]
-		if config.Targets[t].Timeout == 0 {
-			target.Timeout = config.DefaultTimeout
+		config.applyDefaults(&target)
+		if _, found := SupportedBackoffs[target.Backoff]; !found {
+			return nil, &ConfigError{Err: fmt.Errorf("unknown backoff %s for target %s", target.Backoff, t)}
+		}
+		if target.Mode != ModeUp && target.Mode != ModeDown {
+			return nil, &ConfigError{Err: fmt.Errorf("unknown mode %s for target %s", target.Mode, t)}
 		}
-		if config.Targets[t].HTTPClientTimeout == 0 {
-			target.HTTPClientTimeout = config.DefaultHTTPClientTimeout
+		if err := target.Auth.check(); err != nil {
+			return nil, &ConfigError{Err: fmt.Errorf("%v for target %s", err, t)}
 		}
-		if config.Targets[t].StatusPattern == "" {
-			target.StatusPattern = config.DefaultStatusPattern
+		if err := target.TLS.check(); err != nil {
+			return nil, &ConfigError{Err: fmt.Errorf("%v for target %s", err, t)}
+		}
+		if err := target.checkExpectations(); err != nil {
+			return nil, &ConfigError{Err: fmt.Errorf("%v for target %s", err, t)}
 		}
 		config.Targets[t] = target
 	}
+	if err := checkDependencies(config.Targets); err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+	if err := config.checkGroups(); err != nil {
+		return nil, &ConfigError{Err: err}
+	}
 	return &config, nil
 }
 
+// applyDefaults fills in any settings that the target doesn't specify itself
+func (c *Config) applyDefaults(target *TargetConfig) {
+	if target.Timeout == 0 {
+		target.Timeout = c.DefaultTimeout
+	}
+	if target.Mode == "" {
+		target.Mode = c.DefaultMode
+	}
+	if target.HTTPClientTimeout == 0 {
+		target.HTTPClientTimeout = target.AttemptTimeout
+	}
+	if target.HTTPClientTimeout == 0 {
+		target.HTTPClientTimeout = c.DefaultHTTPClientTimeout
+	}
+	if target.AttemptTimeout == 0 {
+		target.AttemptTimeout = c.DefaultAttemptTimeout
+	}
+	if target.StatusPattern == "" {
+		target.StatusPattern = c.DefaultStatusPattern
+	}
+	if target.HTTPMethod == "" {
+		target.HTTPMethod = c.DefaultHTTPMethod
+	}
+	if len(c.DefaultHTTPHeaders) > 0 {
+		headers := map[string]string{}
+		for k, v := range c.DefaultHTTPHeaders {
+			headers[http.CanonicalHeaderKey(k)] = v
+		}
+		for k, v := range target.HTTPHeaders {
+			headers[http.CanonicalHeaderKey(k)] = v
+		}
+		target.HTTPHeaders = headers
+	}
+	if target.HTTPBody == "" {
+		target.HTTPBody = c.DefaultHTTPBody
+	}
+	if target.HTTPExpectBody == nil {
+		target.HTTPExpectBody = c.DefaultHTTPExpectBody
+	}
+	if target.HTTPExpectHeaders == nil {
+		target.HTTPExpectHeaders = c.DefaultHTTPExpectHeaders
+	}
+	if target.Auth == nil {
+		target.Auth = c.DefaultAuth
+	}
+	// TCP and gRPC targets only use TLS when it is asked for on the target itself
+	if target.TLS == nil && target.Type == "http" {
+		target.TLS = c.DefaultTLS
+	}
+	if target.Interval == 0 {
+		target.Interval = c.DefaultInterval
+	}
+	if target.Backoff == "" {
+		target.Backoff = c.DefaultBackoff
+	}
+	if target.MaxInterval == 0 {
+		target.MaxInterval = c.DefaultMaxInterval
+	}
+	if target.SuccessThreshold == 0 {
+		target.SuccessThreshold = c.DefaultSuccessThreshold
+	}
+	if target.StableFor == 0 {
+		target.StableFor = c.DefaultStableFor
+	}
+}
+
+// interval is the pause between attempts, falling back to DefaultInterval if the target
+// doesn't specify its own
+func (t TargetConfig) interval() time.Duration {
+	if t.Interval == 0 {
+		return DefaultInterval
+	}
+	return t.Interval
+}
+
+// attemptTimeout is the longest that a single attempt to reach the target can take, taking
+// in to account any timeouts that are specific to the type of target. DNS targets aren't
+// limited as each attempt watches for a change from the records that it first sees, so
+// cutting it short would start again from the new records and miss the change.
+func (t TargetConfig) attemptTimeout() time.Duration {
+	if t.Type == "dns" {
+		return 0
+	}
+	if t.Type == "http" && t.HTTPClientTimeout > 0 {
+		return t.HTTPClientTimeout
+	}
+	return t.AttemptTimeout
+}
+
 // GotTarget returns true if the target exists in this config
 func (c *Config) GotTarget(t string) bool {
 	_, ok := c.Targets[t]
 	return ok
 }
 
-// AddFromString adds a new target from a string using the format <type>:<target location>
-func (c *Config) AddFromString(t string) error {
-	if strings.HasPrefix(t, "tcp:") {
-		c.Targets[t] = TargetConfig{
-			Target:  strings.Replace(t, "tcp:", "", 1),
-			Type:    "tcp",
-			Timeout: c.DefaultTimeout,
+// GotGroup returns true if the group exists in this config
+func (c *Config) GotGroup(g string) bool {
+	_, ok := c.Groups[g]
+	return ok
+}
+
+// checkGroups makes sure that groups only contain known targets and can be satisfied
+func (c *Config) checkGroups() error {
+	for name, group := range c.Groups {
+		if c.GotTarget(name) {
+			return fmt.Errorf("group %s has the same name as a target", name)
+		}
+		for _, target := range group.Targets {
+			if !c.GotTarget(target) {
+				return fmt.Errorf("group %s contains unknown target %s", name, target)
+			}
+		}
+		if _, err := requiredCount(group.Require, len(group.Targets)); err != nil {
+			return fmt.Errorf("group %s: %v", name, err)
 		}
-		return nil
 	}
+	return nil
+}
 
-	if strings.HasPrefix(t, "http:") || strings.HasPrefix(t, "https:") {
-		c.Targets[t] = TargetConfig{
-			Target:            t,
-			Type:              "http",
-			Timeout:           c.DefaultTimeout,
-			HTTPClientTimeout: c.DefaultHTTPClientTimeout,
-			StatusPattern:     c.DefaultStatusPattern,
+// requirementFor creates the requirement for waiting on the targets and groups named, using
+// the Require setting to decide how many of them need to be ready
+func (c *Config) requirementFor(names []string) (*requirement, error) {
+	r := &requirement{}
+
+	for _, name := range names {
+		group, found := c.Groups[name]
+		if !found {
+			r.items = append(r.items, &requirement{target: name})
+			continue
+		}
+
+		g := &requirement{name: name}
+		for _, target := range group.Targets {
+			g.items = append(g.items, &requirement{target: target})
 		}
-		return nil
-	}
 
-	if strings.HasPrefix(t, "dns:") {
-		c.Targets[t] = TargetConfig{
-			Target:  strings.Replace(t, "dns:", "", 1),
-			Type:    "dns",
-			Timeout: c.DefaultTimeout,
+		var err error
+		g.required, err = requiredCount(group.Require, len(g.items))
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %v", name, err)
 		}
-		return nil
+		r.items = append(r.items, g)
 	}
 
-	return errors.New("unable to understand target " + t)
+	var err error
+	r.required, err = requiredCount(c.Require, len(r.items))
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
 }
 
+// AddFromString adds a new target from a string using the format <type>:<target location>. Prefixing
+// the string with ! waits for the target to become unavailable.
+func (c *Config) AddFromString(t string) error {
+	var target TargetConfig
+
+	location := t
+	if strings.HasPrefix(location, "!") {
+		location = strings.TrimPrefix(location, "!")
+		target.Mode = ModeDown
+	}
+
+	switch {
+	case strings.HasPrefix(location, "tcp:"):
+		target.Target = strings.Replace(location, "tcp:", "", 1)
+		target.Type = "tcp"
+	case strings.HasPrefix(location, "http:") || strings.HasPrefix(location, "https:"):
+		target.Target = location
+		target.Type = "http"
+	case strings.HasPrefix(location, "dns:"):
+		target.Target = strings.Replace(location, "dns:", "", 1)
+		target.Type = "dns"
+	default:
+		return &InvalidTargetError{Target: t}
+	}
+
+	c.applyDefaults(&target)
+	c.Targets[t] = target
+	return nil
+}
+
+// Filter creates a new Config with only the targets listed, along with any targets that they depend on
 func (c *Config) Filter(targets []string) *Config {
 	result := NewConfig()
 
-	for _, target := range targets {
-		if c.GotTarget(target) {
-			result.Targets[target] = c.Targets[target]
+	var add func(target string)
+	add = func(target string) {
+		if result.GotTarget(target) || !c.GotTarget(target) {
+			return
+		}
+		result.Targets[target] = c.Targets[target]
+		for _, dep := range c.Targets[target].DependsOn {
+			add(dep)
 		}
 	}
 
+	for _, target := range targets {
+		add(target)
+	}
+
 	return result
 }
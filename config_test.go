@@ -1,11 +1,13 @@
 package waitfor
 
 import (
+	"errors"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig_fromYAML(t *testing.T) {
@@ -71,6 +73,467 @@ targets:
 	assert.Equal(t, time.Second*18, config.Targets["http-connection"].HTTPClientTimeout)
 }
 
+func TestConfig_intervalAndBackoffCanBeSet(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`
+default-interval: 3s
+default-backoff: linear
+targets:
+  http-connection:
+    type: http
+    target: http://localhost/health
+  tcp-connection:
+    type: tcp
+    target: localhost:80
+    interval: 5s
+    backoff: exponential
+    max-interval: 1m`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second*3, config.Targets["http-connection"].Interval)
+	assert.Equal(t, "linear", config.Targets["http-connection"].Backoff)
+	assert.Equal(t, DefaultMaxInterval, config.Targets["http-connection"].MaxInterval)
+	assert.Equal(t, time.Second*5, config.Targets["tcp-connection"].Interval)
+	assert.Equal(t, "exponential", config.Targets["tcp-connection"].Backoff)
+	assert.Equal(t, time.Minute, config.Targets["tcp-connection"].MaxInterval)
+}
+
+func TestConfig_overridesReplaceDefaultsFromTheFile(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`
+default-interval: 3s
+default-backoff: linear
+targets:
+  http-connection:
+    type: http
+    target: http://localhost/health
+  tcp-connection:
+    type: tcp
+    target: localhost:80
+    interval: 5s`), func(c *Config) {
+		c.DefaultInterval = time.Millisecond * 100
+		c.DefaultSuccessThreshold = 3
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Millisecond*100, config.DefaultInterval)
+	assert.Equal(t, time.Millisecond*100, config.Targets["http-connection"].Interval)
+	assert.Equal(t, "linear", config.Targets["http-connection"].Backoff)
+	assert.Equal(t, 3, config.Targets["http-connection"].SuccessThreshold)
+	assert.Equal(t, time.Second*5, config.Targets["tcp-connection"].Interval)
+	assert.Equal(t, 3, config.Targets["tcp-connection"].SuccessThreshold)
+}
+
+func TestConfig_unknownBackoffFails(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`targets:
+  http-connection:
+    type: http
+    target: http://localhost/health
+    backoff: sometimes`))
+
+	assert.Error(t, err)
+	assert.Nil(t, config)
+}
+
+func TestConfig_attemptTimeoutCanBeSet(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`
+default-attempt-timeout: 2s
+targets:
+  tcp-connection:
+    type: tcp
+    target: localhost:80
+  http-connection:
+    type: http
+    target: http://localhost/health
+  http-attempt:
+    type: http
+    target: http://localhost/health
+    attempt-timeout: 3s
+  http-override:
+    type: http
+    target: http://localhost/health
+    attempt-timeout: 3s
+    http-client-timeout: 4s
+  dns-connection:
+    type: dns
+    target: some.dns.com`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second*2, config.Targets["tcp-connection"].attemptTimeout())
+	assert.Equal(t, time.Second*2, config.Targets["http-connection"].attemptTimeout())
+	assert.Equal(t, time.Second*3, config.Targets["http-attempt"].attemptTimeout())
+	assert.Equal(t, time.Second*4, config.Targets["http-override"].attemptTimeout())
+	assert.Equal(t, time.Duration(0), config.Targets["dns-connection"].attemptTimeout())
+}
+
+func TestConfig_successThresholdCanBeSet(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`
+default-success-threshold: 2
+targets:
+  http-connection:
+    type: http
+    target: http://localhost/health
+  tcp-connection:
+    type: tcp
+    target: localhost:80
+    success-threshold: 5
+    stable-for: 10s`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, config.Targets["http-connection"].SuccessThreshold)
+	assert.Equal(t, time.Duration(0), config.Targets["http-connection"].StableFor)
+	assert.Equal(t, 5, config.Targets["tcp-connection"].SuccessThreshold)
+	assert.Equal(t, time.Second*10, config.Targets["tcp-connection"].StableFor)
+}
+
+func TestConfig_httpRequestCanBeSet(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`
+default-http-headers:
+  X-Api-Key: secret
+  Accept: application/json
+targets:
+  http-connection:
+    type: http
+    target: http://localhost/health
+  http-post:
+    type: http
+    target: http://localhost/check
+    http-method: POST
+    http-headers:
+      accept: text/plain
+      Host: api.internal
+    http-body: '{"check":"all"}'`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "GET", config.Targets["http-connection"].HTTPMethod)
+	assert.Equal(t, map[string]string{"X-Api-Key": "secret", "Accept": "application/json"}, config.Targets["http-connection"].HTTPHeaders)
+	assert.Equal(t, "", config.Targets["http-connection"].HTTPBody)
+	assert.Equal(t, "POST", config.Targets["http-post"].HTTPMethod)
+	assert.Equal(t, map[string]string{"X-Api-Key": "secret", "Accept": "text/plain", "Host": "api.internal"}, config.Targets["http-post"].HTTPHeaders)
+	assert.Equal(t, `{"check":"all"}`, config.Targets["http-post"].HTTPBody)
+}
+
+func TestConfig_expectedBodyCanBeSet(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`
+default-http-expect-body:
+  contains: ok
+targets:
+  http-connection:
+    type: http
+    target: http://localhost/health
+  http-json:
+    type: http
+    target: http://localhost/status
+    http-expect-body:
+      matches: ^\{
+      json:
+        checks.0.status: ok
+      json-fields:
+        status: ok`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &BodyExpectation{Contains: "ok"}, config.Targets["http-connection"].HTTPExpectBody)
+	assert.Equal(t, &BodyExpectation{
+		Matches:    `^\{`,
+		JSON:       map[string]string{"checks.0.status": "ok"},
+		JSONFields: map[string]string{"status": "ok"},
+	}, config.Targets["http-json"].HTTPExpectBody)
+}
+
+func TestConfig_invalidBodyRegexFails(t *testing.T) {
+	_, err := NewConfigFromFile(strings.NewReader(`
+targets:
+  http-connection:
+    type: http
+    target: http://localhost/health
+    http-expect-body:
+      matches: "("`))
+
+	var configErr *ConfigError
+	require.True(t, errors.As(err, &configErr))
+	assert.Contains(t, err.Error(), "invalid body regex")
+	assert.Contains(t, err.Error(), "for target http-connection")
+}
+
+func TestConfig_expectedHeadersCanBeSet(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`
+default-http-expect-headers:
+  - name: X-Ready
+targets:
+  http-connection:
+    type: http
+    target: http://localhost/health
+  http-version:
+    type: http
+    target: http://localhost/version
+    http-expect-headers:
+      - name: X-App-Version
+        equals: 1.5.0
+      - name: Content-Type
+        matches: json$`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []HeaderExpectation{{Name: "X-Ready"}}, config.Targets["http-connection"].HTTPExpectHeaders)
+	assert.Equal(t, []HeaderExpectation{
+		{Name: "X-App-Version", Equals: "1.5.0"},
+		{Name: "Content-Type", Matches: "json$"},
+	}, config.Targets["http-version"].HTTPExpectHeaders)
+}
+
+func TestConfig_invalidHeaderRegexFails(t *testing.T) {
+	_, err := NewConfigFromFile(strings.NewReader(`
+targets:
+  http-connection:
+    type: http
+    target: http://localhost/health
+    http-expect-headers:
+      - name: X-App-Version
+        matches: "("`))
+
+	var configErr *ConfigError
+	require.True(t, errors.As(err, &configErr))
+	assert.Contains(t, err.Error(), "invalid regex for header X-App-Version")
+	assert.Contains(t, err.Error(), "for target http-connection")
+}
+
+func TestConfig_tlsCanBeSet(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`
+default-tls:
+  ca-file: /etc/ssl/internal-ca.pem
+targets:
+  http-connection:
+    type: http
+    target: https://localhost/health
+  grpc-connection:
+    type: grpc
+    target: localhost:9092
+    tls:
+      cert-file: client.pem
+      key-file: client-key.pem
+      server-name: grpc.internal
+      min-version: "1.3"
+      insecure-skip-verify: true`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &TLSConfig{CAFile: "/etc/ssl/internal-ca.pem"}, config.Targets["http-connection"].TLS)
+	assert.Equal(t, &TLSConfig{
+		CertFile:           "client.pem",
+		KeyFile:            "client-key.pem",
+		ServerName:         "grpc.internal",
+		MinVersion:         "1.3",
+		InsecureSkipVerify: true,
+	}, config.Targets["grpc-connection"].TLS)
+}
+
+func TestConfig_defaultTLSOnlyAppliesToHTTP(t *testing.T) {
+	config := NewConfig()
+	config.DefaultTLS = &TLSConfig{CAFile: "ca.pem"}
+
+	assert.NoError(t, config.AddFromString("https://api:8443/health"))
+	assert.NoError(t, config.AddFromString("tcp:db:5432"))
+
+	assert.Equal(t, &TLSConfig{CAFile: "ca.pem"}, config.Targets["https://api:8443/health"].TLS)
+	assert.Nil(t, config.Targets["tcp:db:5432"].TLS)
+
+	config, err := NewConfigFromFile(strings.NewReader(`
+default-tls:
+  insecure-skip-verify: true
+targets:
+  plain-tcp:
+    type: tcp
+    target: localhost:5432
+  plain-grpc:
+    type: grpc
+    target: localhost:9092
+  tls-tcp:
+    type: tcp
+    target: localhost:6380
+    tls:
+      server-name: redis.internal`))
+
+	assert.NoError(t, err)
+	assert.Nil(t, config.Targets["plain-tcp"].TLS)
+	assert.Nil(t, config.Targets["plain-grpc"].TLS)
+	assert.Equal(t, &TLSConfig{ServerName: "redis.internal"}, config.Targets["tls-tcp"].TLS)
+}
+
+func TestConfig_invalidTLSFails(t *testing.T) {
+	_, err := NewConfigFromFile(strings.NewReader(`
+targets:
+  http-connection:
+    type: http
+    target: https://localhost/health
+    tls:
+      min-version: "1.4"`))
+
+	assert.EqualError(t, err, "unknown TLS version 1.4 for target http-connection")
+}
+
+func TestConfig_authCanBeSet(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`
+default-auth:
+  token-file: /var/run/secrets/token
+targets:
+  http-connection:
+    type: http
+    target: http://localhost/health
+  http-basic:
+    type: http
+    target: http://localhost/status
+    auth:
+      username: health
+      password-env: HEALTH_PASSWORD`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &HTTPAuth{TokenFile: "/var/run/secrets/token"}, config.Targets["http-connection"].Auth)
+	assert.Equal(t, &HTTPAuth{Username: "health", PasswordEnv: "HEALTH_PASSWORD"}, config.Targets["http-basic"].Auth)
+}
+
+func TestConfig_invalidAuthFails(t *testing.T) {
+	_, err := NewConfigFromFile(strings.NewReader(`
+targets:
+  http-connection:
+    type: http
+    target: http://localhost/health
+    auth:
+      username: health
+      token: abc`))
+
+	assert.EqualError(t, err, "auth can use a username and password or a token, not both for target http-connection")
+}
+
+func TestConfig_invalidStatusPatternFails(t *testing.T) {
+	_, err := NewConfigFromFile(strings.NewReader(`
+targets:
+  http-connection:
+    type: http
+    target: http://localhost/health
+    http-client-status-pattern: "[2"`))
+
+	var configErr *ConfigError
+	require.True(t, errors.As(err, &configErr))
+	assert.Contains(t, err.Error(), "invalid status pattern [2")
+	assert.Contains(t, err.Error(), "for target http-connection")
+}
+
+func TestConfig_globalTimeoutCanBeSet(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`
+global-timeout: 90s
+targets:
+  tcp-connection:
+    type: tcp
+    target: localhost:80`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second*90, config.GlobalTimeout)
+}
+
+func TestConfig_dependenciesCanBeSet(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`targets:
+  db:
+    type: tcp
+    target: localhost:5432
+  api:
+    type: http
+    target: http://localhost/health
+    depends-on: [db]`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db"}, config.Targets["api"].DependsOn)
+}
+
+func TestConfig_unknownDependencyFails(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`targets:
+  api:
+    type: http
+    target: http://localhost/health
+    depends-on: [db]`))
+
+	assert.Error(t, err)
+	assert.Nil(t, config)
+}
+
+func TestConfig_dependencyCycleFails(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`targets:
+  db:
+    type: tcp
+    target: localhost:5432
+    depends-on: [api]
+  api:
+    type: http
+    target: http://localhost/health
+    depends-on: [db]`))
+
+	assert.Error(t, err)
+	assert.Nil(t, config)
+}
+
+func TestConfig_groupsCanBeSet(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`
+require: any
+targets:
+  etcd-1:
+    type: tcp
+    target: etcd-1:2379
+  etcd-2:
+    type: tcp
+    target: etcd-2:2379
+  etcd-3:
+    type: tcp
+    target: etcd-3:2379
+groups:
+  etcd:
+    targets: [etcd-1, etcd-2, etcd-3]
+    require: 2`))
+
+	require.NoError(t, err)
+	assert.Equal(t, RequireAny, config.Require)
+	assert.True(t, config.GotGroup("etcd"))
+	assert.False(t, config.GotGroup("etcd-1"))
+	assert.Equal(t, []string{"etcd-1", "etcd-2", "etcd-3"}, config.Groups["etcd"].Targets)
+	assert.Equal(t, "2", config.Groups["etcd"].Require)
+}
+
+func TestConfig_invalidGroupsFail(t *testing.T) {
+	tests := map[string]string{
+		"unknown target": `targets:
+  etcd-1:
+    type: tcp
+    target: etcd-1:2379
+groups:
+  etcd:
+    targets: [etcd-1, etcd-2]`,
+		"name clash": `targets:
+  etcd:
+    type: tcp
+    target: etcd-1:2379
+groups:
+  etcd:
+    targets: [etcd]`,
+		"quorum too large": `targets:
+  etcd-1:
+    type: tcp
+    target: etcd-1:2379
+groups:
+  etcd:
+    targets: [etcd-1]
+    require: 2`,
+	}
+
+	for name, yaml := range tests {
+		config, err := NewConfigFromFile(strings.NewReader(yaml))
+		assert.Error(t, err, name)
+		assert.Nil(t, config, name)
+	}
+}
+
+func TestConfig_targetsCanBeAddedWhenFileHasNone(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`
+default-backoff: exponential`))
+
+	require.NoError(t, err)
+	assert.NoError(t, config.AddFromString("tcp:localhost:80"))
+	assert.Equal(t, "exponential", config.Targets["tcp:localhost:80"].Backoff)
+}
+
 func TestConfig_GotTarget(t *testing.T) {
 	config, _ := NewConfigFromFile(strings.NewReader(defaultConfigYaml()))
 
@@ -109,11 +572,74 @@ func TestConfig_AddFromString(t *testing.T) {
 	assert.Equal(t, "tcp", config.Targets["tcp:listener-tcp:9090"].Type)
 	assert.Equal(t, time.Second*5, config.Targets["tcp:listener-tcp:9090"].Timeout)
 
+	assert.Equal(t, DefaultInterval, config.Targets["tcp:listener-tcp:9090"].Interval)
+	assert.Equal(t, DefaultBackoff, config.Targets["tcp:listener-tcp:9090"].Backoff)
+
 	assert.Equal(t, "some.dns.com", config.Targets["dns:some.dns.com"].Target)
 	assert.Equal(t, "dns", config.Targets["dns:some.dns.com"].Type)
 	assert.Equal(t, time.Second*5, config.Targets["dns:some.dns.com"].Timeout)
 }
 
+func TestConfig_AddFromStringWaitingForDown(t *testing.T) {
+	config := NewConfig()
+
+	assert.NoError(t, config.AddFromString("!tcp:listener-tcp:9090"))
+	assert.NoError(t, config.AddFromString("tcp:another-tcp:9090"))
+	assert.Error(t, config.AddFromString("!udp:some-listener:9090"))
+
+	assert.Equal(t, "listener-tcp:9090", config.Targets["!tcp:listener-tcp:9090"].Target)
+	assert.Equal(t, "tcp", config.Targets["!tcp:listener-tcp:9090"].Type)
+	assert.Equal(t, ModeDown, config.Targets["!tcp:listener-tcp:9090"].Mode)
+	assert.Equal(t, ModeUp, config.Targets["tcp:another-tcp:9090"].Mode)
+
+	config.DefaultMode = ModeDown
+	assert.NoError(t, config.AddFromString("http://some-host/endpoint"))
+	assert.Equal(t, ModeDown, config.Targets["http://some-host/endpoint"].Mode)
+}
+
+func TestConfig_modeCanBeSet(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`targets:
+  http-connection:
+    type: http
+    target: http://localhost/health
+  tcp-connection:
+    type: tcp
+    target: localhost:80
+    mode: down`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, ModeUp, config.Targets["http-connection"].Mode)
+	assert.Equal(t, ModeDown, config.Targets["tcp-connection"].Mode)
+}
+
+func TestConfig_defaultModeCanBeOverridden(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`targets:
+  http-connection:
+    type: http
+    target: http://localhost/health
+  tcp-connection:
+    type: tcp
+    target: localhost:80
+    mode: up`), func(c *Config) {
+		c.DefaultMode = ModeDown
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, ModeDown, config.Targets["http-connection"].Mode)
+	assert.Equal(t, ModeUp, config.Targets["tcp-connection"].Mode)
+}
+
+func TestConfig_unknownModeFails(t *testing.T) {
+	config, err := NewConfigFromFile(strings.NewReader(`targets:
+  tcp-connection:
+    type: tcp
+    target: localhost:80
+    mode: sideways`))
+
+	assert.Error(t, err)
+	assert.Nil(t, config)
+}
+
 func TestConfig_Filters(t *testing.T) {
 	config := NewConfig()
 
@@ -128,6 +654,20 @@ func TestConfig_Filters(t *testing.T) {
 	assert.Equal(t, 2, len(filtered.Targets))
 }
 
+func TestConfig_FilterIncludesDependencies(t *testing.T) {
+	config := NewConfig()
+	config.Targets["db"] = TargetConfig{Target: "localhost:5432"}
+	config.Targets["migration"] = TargetConfig{Target: "http://localhost/migrated", DependsOn: []string{"db"}}
+	config.Targets["api"] = TargetConfig{Target: "http://localhost/health", DependsOn: []string{"migration"}}
+	config.Targets["other"] = TargetConfig{Target: "localhost:80"}
+
+	filtered := config.Filter([]string{"api"})
+	assert.Equal(t, 3, len(filtered.Targets))
+	assert.True(t, filtered.GotTarget("db"))
+	assert.True(t, filtered.GotTarget("migration"))
+	assert.False(t, filtered.GotTarget("other"))
+}
+
 func defaultConfigYaml() string {
 	return `targets:
   http-connection:
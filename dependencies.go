@@ -0,0 +1,114 @@
+package waitfor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// checkDependencies makes sure that every dependency refers to a known target and that
+// there are no cycles, which would leave targets waiting on each other forever
+func checkDependencies(targets map[string]TargetConfig) error {
+	var names []string
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, dep := range targets[name].DependsOn {
+			if _, found := targets[dep]; !found {
+				return fmt.Errorf("target %s depends on unknown target %s", name, dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			for i := range path {
+				if path[i] == name {
+					path = path[i:]
+					break
+				}
+			}
+			return fmt.Errorf("dependency cycle between targets %s -> %s", strings.Join(path, " -> "), name)
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range targets[name].DependsOn {
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dependency lets targets know when a target that they depend on has finished
+type dependency struct {
+	once  sync.Once
+	done  chan struct{}
+	ready bool
+}
+
+func newDependencies(targets map[string]TargetConfig) map[string]*dependency {
+	deps := map[string]*dependency{}
+	for name := range targets {
+		deps[name] = &dependency{done: make(chan struct{})}
+	}
+	return deps
+}
+
+// finish signals any dependent targets, ready is true if the target is available. Only the
+// first call has any effect.
+func (d *dependency) finish(ready bool) {
+	d.once.Do(func() {
+		d.ready = ready
+		close(d.done)
+	})
+}
+
+// waitForDependencies blocks until all of the targets that name depends on are ready
+func waitForDependencies(ctx context.Context, name string, logger StructuredLogger, dependsOn []string, deps map[string]*dependency) error {
+	if len(dependsOn) == 0 {
+		return nil
+	}
+
+	logger.Info(fmt.Sprintf("%s is waiting for %s", name, strings.Join(dependsOn, ", ")), "target", name, "depends_on", dependsOn)
+	for _, dep := range dependsOn {
+		select {
+		case <-deps[dep].done:
+			if !deps[dep].ready {
+				return fmt.Errorf("%s is not ready", dep)
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for %s: %w", name, ctx.Err())
+		}
+	}
+
+	return nil
+}
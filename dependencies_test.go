@@ -0,0 +1,122 @@
+package waitfor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDependencies_acceptsValidDependencies(t *testing.T) {
+	err := checkDependencies(map[string]TargetConfig{
+		"db":        {},
+		"migration": {DependsOn: []string{"db"}},
+		"api":       {DependsOn: []string{"db", "migration"}},
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestCheckDependencies_failsForUnknownTarget(t *testing.T) {
+	err := checkDependencies(map[string]TargetConfig{
+		"api": {DependsOn: []string{"db"}},
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, "target api depends on unknown target db", err.Error())
+}
+
+func TestCheckDependencies_failsForCycle(t *testing.T) {
+	err := checkDependencies(map[string]TargetConfig{
+		"api":       {DependsOn: []string{"migration"}},
+		"db":        {DependsOn: []string{"api"}},
+		"migration": {DependsOn: []string{"db"}},
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, "dependency cycle between targets api -> migration -> db -> api", err.Error())
+}
+
+func TestCheckDependencies_failsForSelfDependency(t *testing.T) {
+	err := checkDependencies(map[string]TargetConfig{
+		"api": {DependsOn: []string{"api"}},
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, "dependency cycle between targets api -> api", err.Error())
+}
+
+func TestWaitOnTargets_waitsInDependencyOrder(t *testing.T) {
+	var lock sync.Mutex
+	var order []string
+	record := func(name string) {
+		lock.Lock()
+		defer lock.Unlock()
+		order = append(order, name)
+	}
+
+	err := waitOnTargets(
+		context.Background(),
+		NullLogger,
+		map[string]TargetConfig{
+			"api":       {Type: "t", Timeout: time.Second, DependsOn: []string{"migration"}},
+			"migration": {Type: "t", Timeout: time.Second, DependsOn: []string{"db"}},
+			"db":        {Type: "t", Timeout: time.Second},
+		},
+		map[string]Waiter{
+			"t": WaiterFunc(func(name string, target *TargetConfig) error {
+				time.Sleep(time.Millisecond * 20)
+				record(name)
+				return nil
+			}),
+		},
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"db", "migration", "api"}, order)
+}
+
+func TestWaitOnTargets_doesNotStartTargetsWhenDependencyFails(t *testing.T) {
+	started := false
+
+	err := waitOnTargets(
+		context.Background(),
+		NullLogger,
+		map[string]TargetConfig{
+			"api": {Type: "ok", Timeout: time.Second, DependsOn: []string{"db"}},
+			"db":  {Type: "fail", Timeout: time.Millisecond * 100},
+		},
+		map[string]Waiter{
+			"ok": WaiterFunc(func(string, *TargetConfig) error {
+				started = true
+				return nil
+			}),
+			"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+		},
+	)
+
+	require.Error(t, err)
+	assert.Equal(t, "timed out waiting for db: an error", err.Error())
+	assert.False(t, started)
+}
+
+func TestWaitOnTargets_failsForDependencyCycle(t *testing.T) {
+	err := waitOnTargets(
+		context.Background(),
+		NullLogger,
+		map[string]TargetConfig{
+			"api": {Type: "t", DependsOn: []string{"db"}},
+			"db":  {Type: "t", DependsOn: []string{"api"}},
+		},
+		map[string]Waiter{
+			"t": WaiterFunc(func(string, *TargetConfig) error { return nil }),
+		},
+	)
+
+	require.Error(t, err)
+	assert.Equal(t, "dependency cycle between targets api -> db -> api", err.Error())
+}
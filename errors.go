@@ -0,0 +1,96 @@
+package waitfor
+
+import (
+	"fmt"
+	"time"
+)
+
+// ConfigError is returned when the configuration can't be read or doesn't make sense
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidTargetError is returned when a target isn't in the config and can't be understood
+// as a URL or address either
+type InvalidTargetError struct {
+	Target string
+}
+
+func (e *InvalidTargetError) Error() string {
+	return "unable to understand target " + e.Target
+}
+
+// UnknownTypeError is returned when there is no waiter for the type of a target
+type UnknownTypeError struct {
+	Type string
+}
+
+func (e *UnknownTypeError) Error() string {
+	return fmt.Sprintf("unknown target type %s", e.Type)
+}
+
+// ProbeError is returned when an attempt to reach a target fails. Err is the reason
+// that the attempt failed, such as the underlying network error.
+type ProbeError struct {
+	Name     string
+	Attempts int
+	Err      error
+}
+
+func (e *ProbeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProbeError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError is returned when a target didn't become ready before its timeout. If Name
+// is empty then it was the global timeout that ran out. Err is the reason the target
+// wasn't ready, which will be a ProbeError if the last attempt failed.
+type TimeoutError struct {
+	Name    string
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("global timeout of %s exceeded: %v", e.Timeout, e.Err)
+	}
+	return fmt.Sprintf("timed out waiting for %s: %v", e.Name, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// RequirementError is returned when too many of a set of targets have failed for enough of
+// them to be ready, such as with a quorum. Group is empty if the targets weren't a named
+// group and Ready is how many of them were ready when waiting stopped.
+type RequirementError struct {
+	Group    string
+	Required int
+	Total    int
+	Ready    int
+	Err      error
+}
+
+func (e *RequirementError) Error() string {
+	if e.Group != "" {
+		return fmt.Sprintf("group %s needed %d of %d to be ready: %v", e.Group, e.Required, e.Total, e.Err)
+	}
+	return fmt.Sprintf("needed %d of %d to be ready: %v", e.Required, e.Total, e.Err)
+}
+
+func (e *RequirementError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,173 @@
+package waitfor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeoutError_Error(t *testing.T) {
+	err := &TimeoutError{Name: "db", Timeout: time.Second, Err: errors.New("an error")}
+	assert.Equal(t, "timed out waiting for db: an error", err.Error())
+
+	err = &TimeoutError{Timeout: time.Second, Err: errors.New("an error")}
+	assert.Equal(t, "global timeout of 1s exceeded: an error", err.Error())
+}
+
+func TestWaitOn_returnsTimeoutWrappingProbeFailure(t *testing.T) {
+	config := NewConfig()
+	config.Targets["db"] = TargetConfig{Type: "fail", Timeout: time.Millisecond * 30, Interval: time.Millisecond * 10}
+	cause := errors.New("an error")
+
+	err := WaitOn(config, NullLogger, []string{"db"}, map[string]Waiter{
+		"fail": WaiterFunc(func(string, *TargetConfig) error { return cause }),
+	})
+
+	var timeout *TimeoutError
+	require.True(t, errors.As(err, &timeout))
+	assert.Equal(t, "db", timeout.Name)
+	assert.Equal(t, time.Millisecond*30, timeout.Timeout)
+
+	var probe *ProbeError
+	require.True(t, errors.As(err, &probe))
+	assert.Equal(t, "db", probe.Name)
+	assert.GreaterOrEqual(t, probe.Attempts, 2)
+	assert.True(t, errors.Is(err, cause))
+}
+
+func TestWaitOn_returnsGlobalTimeout(t *testing.T) {
+	config := NewConfig()
+	config.GlobalTimeout = time.Millisecond * 20
+	config.Targets["db"] = TargetConfig{Type: "fail", Timeout: time.Second}
+
+	err := WaitOn(config, NullLogger, []string{"db"}, map[string]Waiter{
+		"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+	})
+
+	var timeout *TimeoutError
+	require.True(t, errors.As(err, &timeout))
+	assert.Equal(t, "", timeout.Name)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestWaitOnContext_wrapsCancellation(t *testing.T) {
+	config := NewConfig()
+	config.Targets["db"] = TargetConfig{Type: "fail", Timeout: time.Second}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := WaitOnContext(ctx, config, NullLogger, []string{"db"}, map[string]Waiter{
+		"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+	})
+
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestWaitOn_returnsUnknownType(t *testing.T) {
+	config := NewConfig()
+	config.Targets["db"] = TargetConfig{Type: "unknown"}
+
+	err := WaitOn(config, NullLogger, []string{"db"}, map[string]Waiter{})
+
+	var unknown *UnknownTypeError
+	require.True(t, errors.As(err, &unknown))
+	assert.Equal(t, "unknown", unknown.Type)
+}
+
+func TestWaitOn_returnsInvalidTarget(t *testing.T) {
+	err := WaitOn(NewConfig(), NullLogger, []string{"rubbish"}, map[string]Waiter{})
+
+	var invalid *InvalidTargetError
+	require.True(t, errors.As(err, &invalid))
+	assert.Equal(t, "rubbish", invalid.Target)
+}
+
+func TestWaitOn_returnsConfigErrorForBadRequirement(t *testing.T) {
+	config := NewConfig()
+	config.Require = "lots"
+	config.Targets["db"] = TargetConfig{Type: "tcp"}
+
+	err := WaitOn(config, NullLogger, []string{"db"}, map[string]Waiter{})
+
+	var configErr *ConfigError
+	assert.True(t, errors.As(err, &configErr))
+}
+
+func TestWaitOn_returnsConfigErrorForInvalidStatusPattern(t *testing.T) {
+	config := NewConfig()
+	config.Targets["api"] = TargetConfig{Type: "http", Target: "http://localhost/health", StatusPattern: "[2"}
+
+	err := WaitOn(config, NullLogger, []string{"api"}, map[string]Waiter{"http": WaiterFunc(HTTPWaiter)})
+
+	var configErr *ConfigError
+	require.True(t, errors.As(err, &configErr))
+	assert.Contains(t, err.Error(), "invalid status pattern [2")
+}
+
+func TestWaitOn_returnsConfigErrorForInvalidBodyRegex(t *testing.T) {
+	config := NewConfig()
+	config.Targets["api"] = TargetConfig{
+		Type:           "http",
+		Target:         "http://localhost/health",
+		StatusPattern:  DefaultStatusPattern,
+		HTTPExpectBody: &BodyExpectation{Matches: "("},
+	}
+
+	err := WaitOn(config, NullLogger, []string{"api"}, map[string]Waiter{"http": WaiterFunc(HTTPWaiter)})
+
+	var configErr *ConfigError
+	require.True(t, errors.As(err, &configErr))
+	assert.Contains(t, err.Error(), "invalid body regex")
+}
+
+func TestWaitOnResults_returnsTimeoutWhenDependencyIsNotReady(t *testing.T) {
+	config := NewConfig()
+	config.Require = RequireAny
+	config.Targets["db"] = TargetConfig{Type: "fail", Timeout: time.Millisecond * 10}
+	config.Targets["api"] = TargetConfig{Type: "ok", Timeout: time.Second, DependsOn: []string{"db"}}
+	config.Targets["cache"] = TargetConfig{Type: "fail", Timeout: time.Millisecond * 200}
+
+	results, err := WaitOnResults(context.Background(), config, NullLogger, []string{"api", "cache", "db"}, map[string]Waiter{
+		"ok":   WaiterFunc(func(string, *TargetConfig) error { return nil }),
+		"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+	})
+	require.Error(t, err)
+	require.Len(t, results, 3)
+
+	var timeoutErr *TimeoutError
+	assert.Equal(t, "api", results[0].Name)
+	assert.Equal(t, StatusFailed, results[0].Status)
+	require.True(t, errors.As(results[0].LastError, &timeoutErr))
+	assert.Equal(t, "api", timeoutErr.Name)
+	assert.Equal(t, "timed out waiting for api: db is not ready", results[0].LastError.Error())
+}
+
+func TestNewConfigFromFile_returnsConfigError(t *testing.T) {
+	_, err := NewConfigFromFile(strings.NewReader("targets: [what"))
+
+	var configErr *ConfigError
+	assert.True(t, errors.As(err, &configErr))
+}
+
+func TestOpenConfig_wrapsMissingFile(t *testing.T) {
+	_, err := OpenConfig("missing.yaml", "1s", "1s", "", afero.NewMemMapFs())
+
+	var configErr *ConfigError
+	assert.True(t, errors.As(err, &configErr))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestTCPWaiter_wrapsNetworkErrors(t *testing.T) {
+	err := TCPWaiterContext(context.Background(), "db", &TargetConfig{Target: "localhost:1"})
+
+	var opErr *net.OpError
+	assert.True(t, errors.As(err, &opErr))
+}
@@ -0,0 +1,67 @@
+package waitfor
+
+import (
+	"time"
+)
+
+// EventType is the kind of thing that happened while waiting on a target
+type EventType string
+
+const (
+	// EventAttempt is sent after each attempt to reach a target
+	EventAttempt EventType = "attempt"
+	// EventStatus is sent when a target starts being waited on and when it finishes
+	EventStatus EventType = "status"
+)
+
+const (
+	// StatusWaiting means that the target is being tried, it is only used in events
+	StatusWaiting Status = "waiting"
+	// StatusPending means that the target is waiting for the targets that it depends on to
+	// be ready before it is tried, it is only used in events
+	StatusPending Status = "pending"
+)
+
+// Event describes something that happened while waiting on a target
+type Event struct {
+	// Time is when the event happened
+	Time time.Time
+	// Type is the kind of event
+	Type EventType
+	// Name is the name of the target
+	Name string
+	// Attempt is the number of the attempt, for attempt events
+	Attempt int
+	// Duration is how long the attempt took, for attempt events
+	Duration time.Duration
+	// Status is the new status of the target, for status events
+	Status Status
+	// Err is why the attempt failed, or why the target failed or was cancelled
+	Err error
+}
+
+// EventHandler is told about events as they happen. It may be called from several
+// goroutines at once so it must be safe for concurrent use.
+type EventHandler func(Event)
+
+func (h EventHandler) emit(e Event) {
+	if h == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	h(e)
+}
+
+// eventHandlers combines handlers in to a single handler
+func eventHandlers(handlers []EventHandler) EventHandler {
+	if len(handlers) == 0 {
+		return nil
+	}
+	return func(e Event) {
+		for _, h := range handlers {
+			h(e)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package waitfor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitOnResults_sendsEvents(t *testing.T) {
+	config := NewConfig()
+	config.Targets["flaky"] = TargetConfig{Type: "flaky", Timeout: time.Second, Interval: time.Millisecond}
+
+	attempts := 0
+	var lock sync.Mutex
+	var events []Event
+	_, err := WaitOnResults(context.Background(), config, NullLogger, []string{"flaky"}, map[string]Waiter{
+		"flaky": WaiterFunc(func(string, *TargetConfig) error {
+			attempts++
+			if attempts < 2 {
+				return errors.New("not yet")
+			}
+			return nil
+		}),
+	}, func(e Event) {
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, e)
+	})
+
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+
+	assert.Equal(t, EventStatus, events[0].Type)
+	assert.Equal(t, StatusWaiting, events[0].Status)
+
+	assert.Equal(t, EventAttempt, events[1].Type)
+	assert.Equal(t, "flaky", events[1].Name)
+	assert.Equal(t, 1, events[1].Attempt)
+	assert.EqualError(t, events[1].Err, "not yet")
+
+	assert.Equal(t, EventAttempt, events[2].Type)
+	assert.Equal(t, 2, events[2].Attempt)
+	assert.NoError(t, events[2].Err)
+
+	assert.Equal(t, EventStatus, events[3].Type)
+	assert.Equal(t, StatusReady, events[3].Status)
+	assert.False(t, events[3].Time.IsZero())
+}
+
+func TestWaitOnResults_sendsStatusForEveryTargetBeforeWaiting(t *testing.T) {
+	config := NewConfig()
+	config.Targets["api"] = TargetConfig{Type: "ok", Timeout: time.Second, DependsOn: []string{"db"}}
+	config.Targets["db"] = TargetConfig{Type: "ok", Timeout: time.Second}
+
+	var lock sync.Mutex
+	var events []Event
+	_, err := WaitOnResults(context.Background(), config, NullLogger, []string{"api"}, map[string]Waiter{
+		"ok": WaiterFunc(func(string, *TargetConfig) error { return nil }),
+	}, func(e Event) {
+		lock.Lock()
+		defer lock.Unlock()
+		events = append(events, e)
+	})
+
+	require.NoError(t, err)
+	require.Len(t, events, 7)
+
+	assert.Equal(t, "api", events[0].Name)
+	assert.Equal(t, StatusPending, events[0].Status)
+	assert.Equal(t, "db", events[1].Name)
+	assert.Equal(t, StatusWaiting, events[1].Status)
+
+	var api []Status
+	for _, e := range events {
+		if e.Name == "api" && e.Type == EventStatus {
+			api = append(api, e.Status)
+		}
+	}
+	assert.Equal(t, []Status{StatusPending, StatusWaiting, StatusReady}, api)
+}
+
+func TestWaitOnResults_sendsCancelledStatus(t *testing.T) {
+	config := NewConfig()
+	config.Targets["failed"] = TargetConfig{Type: "fail", Timeout: time.Millisecond * 10}
+	config.Targets["slow"] = TargetConfig{Type: "slow", Timeout: time.Second * 10}
+
+	statuses := map[string]Status{}
+	var lock sync.Mutex
+	_, err := WaitOnResults(context.Background(), config, NullLogger, []string{"failed", "slow"}, map[string]Waiter{
+		"slow": ContextWaiterFunc(func(ctx context.Context, _ string, _ *TargetConfig) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+		"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+	}, func(e Event) {
+		lock.Lock()
+		defer lock.Unlock()
+		if e.Type == EventStatus {
+			statuses[e.Name] = e.Status
+		}
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, map[string]Status{"failed": StatusFailed, "slow": StatusCancelled}, statuses)
+}
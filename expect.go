@@ -0,0 +1,278 @@
+package waitfor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxExpectedBodySize is the most of a response body that is read when checking it
+const maxExpectedBodySize = 1 << 20
+
+// BodyExpectation describes what the body of an HTTP response must contain for the
+// target to be ready. Every expectation that is set must be met.
+type BodyExpectation struct {
+	// Contains is text that must appear somewhere in the body
+	Contains string
+	// Matches is a regular expression that the body must match
+	Matches string
+	// JSON maps gjson-style paths, such as checks.0.status, to the value found at that path
+	JSON map[string]string `yaml:"json"`
+	// JSONFields maps the names of top level fields in the body to their values
+	JSONFields map[string]string `yaml:"json-fields"`
+
+	pattern *regexp.Regexp
+}
+
+// HeaderExpectation describes a header that an HTTP response must have for the target to be
+// ready. The header only has to be present if neither Equals nor Matches is set.
+type HeaderExpectation struct {
+	// Name is the name of the header
+	Name string
+	// Equals is the value that the header must have
+	Equals string
+	// Matches is a regular expression that the value of the header must match
+	Matches string
+
+	pattern *regexp.Regexp
+}
+
+// checkExpectations makes sure that what the target expects of its responses makes sense,
+// so that mistakes are found before any attempts are made
+func (t TargetConfig) checkExpectations() error {
+	return t.compilePatterns()
+}
+
+// compilePatterns compiles the regular expressions that the target's responses are checked
+// against. The expectations are copied rather than changed as they can be shared by targets.
+func (t *TargetConfig) compilePatterns() error {
+	status, err := regexp.Compile(t.StatusPattern)
+	if err != nil {
+		return fmt.Errorf("invalid status pattern %s: %v", t.StatusPattern, err)
+	}
+	t.statusPattern = status
+
+	var headers []HeaderExpectation
+	for _, e := range t.HTTPExpectHeaders {
+		compiled, err := e.compile()
+		if err != nil {
+			return err
+		}
+		headers = append(headers, compiled)
+	}
+	t.HTTPExpectHeaders = headers
+
+	body, err := t.HTTPExpectBody.compile()
+	if err != nil {
+		return err
+	}
+	t.HTTPExpectBody = body
+	t.compiled = true
+
+	return nil
+}
+
+// checkHeaders checks that the headers of a response meet every expectation, which must have
+// been compiled. A header that is repeated meets an expectation if any of its values do.
+func checkHeaders(expect []HeaderExpectation, header http.Header) error {
+	for _, e := range expect {
+		if err := e.checkValues(header.Values(e.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compile returns a copy of the expectation with its regular expression compiled
+func (e HeaderExpectation) compile() (HeaderExpectation, error) {
+	if e.Name == "" {
+		return e, fmt.Errorf("header expectations need a name")
+	}
+	if e.Matches != "" {
+		pattern, err := regexp.Compile(e.Matches)
+		if err != nil {
+			return e, fmt.Errorf("invalid regex for header %s %v", e.Name, err)
+		}
+		e.pattern = pattern
+	}
+	return e, nil
+}
+
+func (e HeaderExpectation) checkValues(values []string) error {
+	if len(values) == 0 {
+		return fmt.Errorf("header %s is missing", e.Name)
+	}
+
+	for _, v := range values {
+		if e.Equals != "" && v != e.Equals {
+			continue
+		}
+		if e.pattern != nil && !e.pattern.MatchString(v) {
+			continue
+		}
+		return nil
+	}
+
+	actual := strings.Join(values, ", ")
+	if e.Equals != "" {
+		return fmt.Errorf("header %s is %q, expected %q", e.Name, actual, e.Equals)
+	}
+	return fmt.Errorf("header %s is %q, which doesn't match %s", e.Name, actual, e.pattern.String())
+}
+
+// compile returns a copy of the expectation with its regular expression compiled
+func (e *BodyExpectation) compile() (*BodyExpectation, error) {
+	if e == nil {
+		return nil, nil
+	}
+
+	compiled := *e
+	if e.Matches != "" {
+		pattern, err := regexp.Compile(e.Matches)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body regex %v", err)
+		}
+		compiled.pattern = pattern
+	}
+	return &compiled, nil
+}
+
+// checkBody reads the body of a response and checks that it meets the expectation, which
+// must have been compiled
+func checkBody(expect *BodyExpectation, body io.Reader) error {
+	if expect == nil {
+		return nil
+	}
+
+	contents, err := io.ReadAll(io.LimitReader(body, maxExpectedBodySize))
+	if err != nil {
+		return fmt.Errorf("unable to read body: %w", err)
+	}
+
+	if expect.Contains != "" && !bytes.Contains(contents, []byte(expect.Contains)) {
+		return fmt.Errorf("body doesn't contain %q", expect.Contains)
+	}
+
+	if expect.pattern != nil && !expect.pattern.Match(contents) {
+		return fmt.Errorf("body doesn't match %s", expect.pattern.String())
+	}
+
+	if len(expect.JSON) == 0 && len(expect.JSONFields) == 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return fmt.Errorf("body isn't JSON: %w", err)
+	}
+
+	for _, path := range sortedKeys(expect.JSON) {
+		value, found := jsonPath(doc, path)
+		if err := checkJSONValue(path, value, found, expect.JSON[path]); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range sortedKeys(expect.JSONFields) {
+		var value interface{}
+		found := false
+		if fields, ok := doc.(map[string]interface{}); ok {
+			value, found = fields[field]
+		}
+		if err := checkJSONValue(field, value, found, expect.JSONFields[field]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkJSONValue compares the value found in a JSON body with the one expected
+func checkJSONValue(path string, value interface{}, found bool, expected string) error {
+	if !found {
+		return fmt.Errorf("%s is missing from the body, expected %q", path, expected)
+	}
+	if actual := jsonString(value); actual != expected {
+		return fmt.Errorf("%s is %q in the body, expected %q", path, actual, expected)
+	}
+	return nil
+}
+
+// jsonPath finds the value in doc at a gjson-style path. The path is made of field names and
+// array indexes separated by dots, with # giving the length of an array. A dot can be used in
+// a field name by escaping it as \.
+func jsonPath(doc interface{}, path string) (interface{}, bool) {
+	value := doc
+	for _, part := range splitJSONPath(path) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			var found bool
+			value, found = v[part]
+			if !found {
+				return nil, false
+			}
+		case []interface{}:
+			if part == "#" {
+				value = json.Number(strconv.Itoa(len(v)))
+				continue
+			}
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func splitJSONPath(path string) []string {
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path):
+			i++
+			part.WriteByte(path[i])
+		case path[i] == '.':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(path[i])
+		}
+	}
+	return append(parts, part.String())
+}
+
+// jsonString turns a JSON value in to the text used to compare it with what is expected.
+// Strings are used as they are, anything else is written as JSON.
+func jsonString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+// sortedKeys lists the keys of m in order so that expectations are always checked the same way
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,221 @@
+package waitfor
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const healthBody = `{
+	"status": "ok",
+	"version": 3,
+	"live": true,
+	"app.name": "orders",
+	"checks": [
+		{"name": "db", "status": "ok"},
+		{"name": "cache", "status": "starting"}
+	]
+}`
+
+func TestCheckHeaders_passesWithoutExpectations(t *testing.T) {
+	assert.NoError(t, checkHeaders(nil, http.Header{}))
+}
+
+func TestCheckHeaders_present(t *testing.T) {
+	header := http.Header{"X-App-Version": {"1.4.2"}}
+
+	assert.NoError(t, checkHeaders([]HeaderExpectation{{Name: "x-app-version"}}, header))
+
+	err := checkHeaders([]HeaderExpectation{{Name: "X-Request-Id"}}, header)
+	assert.EqualError(t, err, "header X-Request-Id is missing")
+}
+
+func TestCheckHeaders_equals(t *testing.T) {
+	header := http.Header{"X-App-Version": {"1.4.2"}}
+
+	assert.NoError(t, checkHeaders([]HeaderExpectation{{Name: "X-App-Version", Equals: "1.4.2"}}, header))
+
+	err := checkHeaders([]HeaderExpectation{{Name: "X-App-Version", Equals: "1.5.0"}}, header)
+	assert.EqualError(t, err, `header X-App-Version is "1.4.2", expected "1.5.0"`)
+}
+
+// compiledHeaders compiles the regular expressions in header expectations as happens before waiting
+func compiledHeaders(t *testing.T, expect ...HeaderExpectation) []HeaderExpectation {
+	var compiled []HeaderExpectation
+	for _, e := range expect {
+		c, err := e.compile()
+		require.NoError(t, err)
+		compiled = append(compiled, c)
+	}
+	return compiled
+}
+
+func TestCheckHeaders_matches(t *testing.T) {
+	header := http.Header{"X-App-Version": {"1.4.2"}}
+
+	assert.NoError(t, checkHeaders(compiledHeaders(t, HeaderExpectation{Name: "X-App-Version", Matches: `^1\.4\.`}), header))
+
+	err := checkHeaders(compiledHeaders(t, HeaderExpectation{Name: "X-App-Version", Matches: `^1\.5\.`}), header)
+	assert.EqualError(t, err, `header X-App-Version is "1.4.2", which doesn't match ^1\.5\.`)
+}
+
+func TestHeaderExpectation_compileFailsForBadSettings(t *testing.T) {
+	compiled, err := HeaderExpectation{Name: "X-App-Version", Matches: `^1\.5\.`}.compile()
+	assert.NoError(t, err)
+	assert.Equal(t, `^1\.5\.`, compiled.pattern.String())
+
+	_, err = HeaderExpectation{Equals: "1.5.0"}.compile()
+	assert.EqualError(t, err, "header expectations need a name")
+
+	_, err = HeaderExpectation{Name: "X-App-Version", Matches: `(`}.compile()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid regex for header X-App-Version")
+}
+
+func TestCheckHeaders_anyRepeatedValueCanMatch(t *testing.T) {
+	header := http.Header{"Vary": {"Accept", "Origin"}}
+
+	assert.NoError(t, checkHeaders([]HeaderExpectation{{Name: "Vary", Equals: "Origin"}}, header))
+
+	err := checkHeaders([]HeaderExpectation{{Name: "Vary", Equals: "Cookie"}}, header)
+	assert.EqualError(t, err, `header Vary is "Accept, Origin", expected "Cookie"`)
+}
+
+func TestCheckHeaders_checksEveryExpectation(t *testing.T) {
+	header := http.Header{"X-App-Version": {"1.4.2"}, "Content-Type": {"application/json"}}
+	expect := []HeaderExpectation{
+		{Name: "Content-Type", Matches: "json"},
+		{Name: "X-App-Version", Equals: "1.4.2"},
+		{Name: "X-Ready"},
+	}
+
+	err := checkHeaders(compiledHeaders(t, expect...), header)
+	assert.EqualError(t, err, "header X-Ready is missing")
+}
+
+func TestCheckBody_passesWithoutExpectation(t *testing.T) {
+	assert.NoError(t, checkBody(nil, strings.NewReader("anything")))
+	assert.NoError(t, checkBody(&BodyExpectation{}, strings.NewReader("anything")))
+}
+
+func TestCheckBody_contains(t *testing.T) {
+	assert.NoError(t, checkBody(&BodyExpectation{Contains: `"status": "ok"`}, strings.NewReader(healthBody)))
+
+	err := checkBody(&BodyExpectation{Contains: "ready"}, strings.NewReader(`{"status":"starting"}`))
+	assert.EqualError(t, err, `body doesn't contain "ready"`)
+}
+
+// compiledBody compiles the regular expression in a body expectation as happens before waiting
+func compiledBody(t *testing.T, expect *BodyExpectation) *BodyExpectation {
+	compiled, err := expect.compile()
+	require.NoError(t, err)
+	return compiled
+}
+
+func TestCheckBody_matches(t *testing.T) {
+	assert.NoError(t, checkBody(compiledBody(t, &BodyExpectation{Matches: `"version": [3-9]`}), strings.NewReader(healthBody)))
+
+	err := checkBody(compiledBody(t, &BodyExpectation{Matches: `^ready$`}), strings.NewReader("starting"))
+	assert.EqualError(t, err, "body doesn't match ^ready$")
+}
+
+func TestBodyExpectation_compileFailsForInvalidRegex(t *testing.T) {
+	compiled, err := (*BodyExpectation)(nil).compile()
+	assert.NoError(t, err)
+	assert.Nil(t, compiled)
+
+	expect := &BodyExpectation{Matches: `^ready$`}
+	compiled, err = expect.compile()
+	assert.NoError(t, err)
+	assert.Equal(t, `^ready$`, compiled.Matches)
+	assert.Nil(t, expect.pattern, "the original expectation isn't changed")
+
+	_, err = (&BodyExpectation{Matches: `(`}).compile()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid body regex")
+}
+
+func TestCheckTargets_compilesPatternsOnceForEachTarget(t *testing.T) {
+	shared := &BodyExpectation{Matches: `ok`}
+	headers := []HeaderExpectation{{Name: "X-App-Version", Matches: `^1\.`}}
+	targets := map[string]TargetConfig{
+		"api": {Type: "http", StatusPattern: DefaultStatusPattern, HTTPExpectBody: shared, HTTPExpectHeaders: headers},
+		"web": {Type: "http", StatusPattern: DefaultStatusPattern, HTTPExpectBody: shared, HTTPExpectHeaders: headers},
+	}
+
+	require.NoError(t, checkTargets(targets, map[string]Waiter{"http": WaiterFunc(HTTPWaiter)}))
+
+	for name, target := range targets {
+		assert.True(t, target.compiled, name)
+		assert.NotNil(t, target.HTTPExpectBody.pattern, name)
+		assert.NotNil(t, target.HTTPExpectHeaders[0].pattern, name)
+	}
+	assert.Nil(t, shared.pattern)
+	assert.Nil(t, headers[0].pattern)
+}
+
+func TestCheckBody_jsonPaths(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "status", expected: "ok"},
+		{path: "version", expected: "3"},
+		{path: "live", expected: "true"},
+		{path: "checks.0.name", expected: "db"},
+		{path: "checks.1.status", expected: "starting"},
+		{path: "checks.#", expected: "2"},
+		{path: `app\.name`, expected: "orders"},
+		{path: "checks.0", expected: `{"name":"db","status":"ok"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			err := checkBody(&BodyExpectation{JSON: map[string]string{test.path: test.expected}}, strings.NewReader(healthBody))
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCheckBody_jsonPathMismatch(t *testing.T) {
+	err := checkBody(&BodyExpectation{JSON: map[string]string{"checks.1.status": "ok"}}, strings.NewReader(healthBody))
+	assert.EqualError(t, err, `checks.1.status is "starting" in the body, expected "ok"`)
+
+	err = checkBody(&BodyExpectation{JSON: map[string]string{"checks.5.status": "ok"}}, strings.NewReader(healthBody))
+	assert.EqualError(t, err, `checks.5.status is missing from the body, expected "ok"`)
+
+	err = checkBody(&BodyExpectation{JSON: map[string]string{"status.deep": "ok"}}, strings.NewReader(healthBody))
+	assert.EqualError(t, err, `status.deep is missing from the body, expected "ok"`)
+}
+
+func TestCheckBody_jsonFields(t *testing.T) {
+	assert.NoError(t, checkBody(&BodyExpectation{JSONFields: map[string]string{"status": "ok", "app.name": "orders"}}, strings.NewReader(healthBody)))
+
+	err := checkBody(&BodyExpectation{JSONFields: map[string]string{"status": "ok"}}, strings.NewReader(`{"status":"starting"}`))
+	assert.EqualError(t, err, `status is "starting" in the body, expected "ok"`)
+
+	err = checkBody(&BodyExpectation{JSONFields: map[string]string{"status": "ok"}}, strings.NewReader(`["status"]`))
+	assert.EqualError(t, err, `status is missing from the body, expected "ok"`)
+}
+
+func TestCheckBody_failsWhenBodyIsNotJSON(t *testing.T) {
+	err := checkBody(&BodyExpectation{JSON: map[string]string{"status": "ok"}}, strings.NewReader("OK"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "body isn't JSON")
+}
+
+func TestCheckBody_checksEveryExpectation(t *testing.T) {
+	expect := &BodyExpectation{
+		Contains:   "checks",
+		Matches:    `"version": \d+`,
+		JSON:       map[string]string{"checks.0.status": "ok"},
+		JSONFields: map[string]string{"status": "ok"},
+	}
+	expect = compiledBody(t, expect)
+	assert.NoError(t, checkBody(expect, strings.NewReader(healthBody)))
+
+	expect.JSON["checks.1.status"] = "ok"
+	assert.Error(t, checkBody(expect, strings.NewReader(healthBody)))
+}
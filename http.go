@@ -0,0 +1,83 @@
+package waitfor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// newHTTPClient creates the client used to make requests to an HTTP target, using the target's
+// TLS settings for https URLs
+func newHTTPClient(target *TargetConfig) (*http.Client, error) {
+	client := &http.Client{
+		Timeout: target.HTTPClientTimeout,
+	}
+
+	tlsConfig, err := newTLSConfig(target.TLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		// Connections aren't reused between attempts so make sure that they get closed
+		transport.DisableKeepAlives = true
+		client.Transport = transport
+	}
+
+	return client, nil
+}
+
+// newHTTPRequest creates the request to make to an HTTP target using the method, headers,
+// body and auth that it has been configured with
+func newHTTPRequest(ctx context.Context, target *TargetConfig) (*http.Request, error) {
+	method := target.HTTPMethod
+	if method == "" {
+		method = DefaultHTTPMethod
+	}
+
+	body, err := httpBody(target.HTTPBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), target.Target, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range target.HTTPHeaders {
+		if http.CanonicalHeaderKey(name) == "Host" {
+			req.Host = value
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+
+	if err := target.Auth.apply(req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// httpBody is the body to send with a request, reading it from a file if it starts with @ so
+// that changes to the file are picked up on the next attempt
+func httpBody(body string) (io.Reader, error) {
+	if body == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(body, "@") {
+		return strings.NewReader(body), nil
+	}
+
+	contents, err := os.ReadFile(strings.TrimPrefix(body, "@"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read body: %w", err)
+	}
+	return bytes.NewReader(contents), nil
+}
@@ -0,0 +1,146 @@
+package waitfor
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requestRecorder is an HTTP server that remembers the last request that it received
+type requestRecorder struct {
+	method string
+	host   string
+	header http.Header
+	body   string
+}
+
+func (r *requestRecorder) server(t *testing.T, status int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.method = req.Method
+		r.host = req.Host
+		r.header = req.Header
+		r.body = string(body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPWaiter_usesGetByDefault(t *testing.T) {
+	recorder := &requestRecorder{}
+	server := recorder.server(t, http.StatusOK)
+
+	err := HTTPWaiterContext(context.Background(), "server", &TargetConfig{Target: server.URL, StatusPattern: DefaultStatusPattern})
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodGet, recorder.method)
+	assert.Equal(t, "", recorder.body)
+}
+
+func TestHTTPWaiter_sendsMethodHeadersAndBody(t *testing.T) {
+	recorder := &requestRecorder{}
+	server := recorder.server(t, http.StatusOK)
+
+	err := HTTPWaiterContext(context.Background(), "server", &TargetConfig{
+		Target:        server.URL,
+		StatusPattern: DefaultStatusPattern,
+		HTTPMethod:    "post",
+		HTTPHeaders: map[string]string{
+			"X-Api-Key":    "secret",
+			"Content-Type": "application/json",
+			"host":         "api.internal",
+		},
+		HTTPBody: `{"check":"all"}`,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPost, recorder.method)
+	assert.Equal(t, "api.internal", recorder.host)
+	assert.Equal(t, "secret", recorder.header.Get("X-Api-Key"))
+	assert.Equal(t, "application/json", recorder.header.Get("Content-Type"))
+	assert.Equal(t, `{"check":"all"}`, recorder.body)
+}
+
+func TestHTTPWaiter_readsBodyFromFile(t *testing.T) {
+	recorder := &requestRecorder{}
+	server := recorder.server(t, http.StatusOK)
+	path := filepath.Join(t.TempDir(), "body.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"from":"file"}`), 0o600))
+
+	err := HTTPWaiterContext(context.Background(), "server", &TargetConfig{
+		Target:        server.URL,
+		StatusPattern: DefaultStatusPattern,
+		HTTPMethod:    http.MethodPut,
+		HTTPBody:      "@" + path,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPut, recorder.method)
+	assert.Equal(t, `{"from":"file"}`, recorder.body)
+}
+
+func TestHTTPWaiter_failsWhenBodyFileIsMissing(t *testing.T) {
+	recorder := &requestRecorder{}
+	server := recorder.server(t, http.StatusOK)
+
+	err := HTTPWaiterContext(context.Background(), "server", &TargetConfig{
+		Target:        server.URL,
+		StatusPattern: DefaultStatusPattern,
+		HTTPBody:      "@" + filepath.Join(t.TempDir(), "missing.json"),
+	})
+
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Contains(t, err.Error(), "could not create request for server: unable to read body")
+	assert.Equal(t, "", recorder.method)
+}
+
+func TestHTTPWaiter_checksBody(t *testing.T) {
+	var body atomic.Value
+	body.Store(`{"status":"starting"}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(body.Load().(string)))
+	}))
+	t.Cleanup(server.Close)
+
+	target := &TargetConfig{
+		Target:         server.URL,
+		StatusPattern:  DefaultStatusPattern,
+		HTTPExpectBody: &BodyExpectation{JSONFields: map[string]string{"status": "ok"}},
+	}
+
+	err := HTTPWaiterContext(context.Background(), "server", target)
+	assert.EqualError(t, err, `status is "starting" in the body, expected "ok"`)
+
+	body.Store(`{"status":"ok"}`)
+	assert.NoError(t, HTTPWaiterContext(context.Background(), "server", target))
+}
+
+func TestHTTPWaiter_checksHeaders(t *testing.T) {
+	var version atomic.Value
+	version.Store("1.4.2")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-App-Version", version.Load().(string))
+	}))
+	t.Cleanup(server.Close)
+
+	target := &TargetConfig{
+		Target:            server.URL,
+		StatusPattern:     DefaultStatusPattern,
+		HTTPExpectHeaders: []HeaderExpectation{{Name: "X-App-Version", Equals: "1.5.0"}},
+	}
+
+	err := HTTPWaiterContext(context.Background(), "server", target)
+	assert.EqualError(t, err, `header X-App-Version is "1.4.2", expected "1.5.0"`)
+
+	version.Store("1.5.0")
+	assert.NoError(t, HTTPWaiterContext(context.Background(), "server", target))
+}
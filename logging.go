@@ -0,0 +1,33 @@
+package waitfor
+
+import (
+	"log/slog"
+)
+
+// StructuredLogger is a levelled logger that takes a message followed by alternating keys
+// and values, such as "target", name. A *slog.Logger can be used directly.
+type StructuredLogger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+var _ StructuredLogger = (*slog.Logger)(nil)
+
+// Logger is a printf style logging function. It implements StructuredLogger by logging just
+// the message at every level apart from debug, so existing logging functions can still be used.
+type Logger func(string, ...interface{})
+
+// NullLogger can be used in place of a real logging function
+var NullLogger Logger = func(f string, a ...interface{}) {}
+
+func (l Logger) Debug(msg string, args ...interface{}) {}
+func (l Logger) Info(msg string, args ...interface{})  { l("%s", msg) }
+func (l Logger) Warn(msg string, args ...interface{})  { l("%s", msg) }
+func (l Logger) Error(msg string, args ...interface{}) { l("%s", msg) }
+
+// targetFields are the fields logged to describe a target
+func targetFields(name string, target *TargetConfig, args ...interface{}) []interface{} {
+	return append([]interface{}{"target", name, "type", target.Type}, args...)
+}
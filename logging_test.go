@@ -0,0 +1,88 @@
+package waitfor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_logsMessageWithoutFields(t *testing.T) {
+	var logs []string
+	var logger Logger = func(f string, a ...interface{}) { logs = append(logs, fmt.Sprintf(f, a...)) }
+
+	logger.Debug("debug %s", "target", "name")
+	logger.Info("info %s", "target", "name")
+	logger.Warn("warn", "target", "name")
+	logger.Error("error", "target", "name")
+
+	assert.Equal(t, []string{"info %s", "warn", "error"}, logs)
+}
+
+func TestWaitOn_acceptsPrintfLoggingFunction(t *testing.T) {
+	config := NewConfig()
+	config.Targets["ready"] = TargetConfig{Type: "ready", Timeout: time.Second}
+
+	var logs []string
+	err := WaitOn(config, func(f string, a ...interface{}) { logs = append(logs, fmt.Sprintf(f, a...)) }, []string{"ready"}, map[string]Waiter{
+		"ready": WaiterFunc(func(string, *TargetConfig) error { return nil }),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"started waiting for ready", "finished waiting for ready"}, logs)
+}
+
+func TestWaitOnResults_logsFieldsToSlog(t *testing.T) {
+	config := NewConfig()
+	config.Targets["flaky"] = TargetConfig{Type: "flaky", Timeout: time.Second, Interval: time.Millisecond}
+
+	var b bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	attempts := 0
+	_, err := WaitOnResults(context.Background(), config, logger, []string{"flaky"}, map[string]Waiter{
+		"flaky": WaiterFunc(func(string, *TargetConfig) error {
+			attempts++
+			if attempts < 2 {
+				return errors.New("not yet")
+			}
+			return nil
+		}),
+	})
+	require.NoError(t, err)
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		record := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+
+	require.Len(t, records, 5)
+	assert.Equal(t, "started waiting for flaky", records[0]["msg"])
+	assert.Equal(t, "INFO", records[0]["level"])
+	assert.Equal(t, "flaky", records[0]["target"])
+	assert.Equal(t, "flaky", records[0]["type"])
+
+	assert.Equal(t, "DEBUG", records[1]["level"])
+	assert.Equal(t, float64(1), records[1]["attempt"])
+	assert.Equal(t, "not yet", records[1]["error"])
+
+	assert.Equal(t, "WARN", records[2]["level"])
+	assert.Equal(t, "error while waiting for flaky: not yet", records[2]["msg"])
+
+	assert.Equal(t, "DEBUG", records[3]["level"])
+	assert.NotContains(t, records[3], "error")
+
+	assert.Equal(t, "finished waiting for flaky", records[4]["msg"])
+	assert.Equal(t, float64(2), records[4]["attempts"])
+	assert.Contains(t, records[4], "elapsed")
+}
@@ -0,0 +1,156 @@
+package waitfor
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// RequireAll means that every target must be ready
+const RequireAll = "all"
+
+// RequireAny means that only one target needs to be ready
+const RequireAny = "any"
+
+// requirement describes how many of a set of targets, or groups of targets, need to be
+// ready. It is either a single target or a number of items, with a name if it's a group.
+type requirement struct {
+	name     string
+	target   string
+	items    []*requirement
+	required int
+}
+
+// requireAll creates a requirement that every one of targets is ready
+func requireAll(targets map[string]TargetConfig) *requirement {
+	var names []string
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	r := &requirement{}
+	for _, name := range names {
+		r.items = append(r.items, &requirement{target: name})
+	}
+	r.required = len(r.items)
+
+	return r
+}
+
+// requiredCount converts a require setting of all, any or a number in to the number of
+// items out of total that must be ready
+func requiredCount(require string, total int) (int, error) {
+	switch require {
+	case "", RequireAll:
+		return total, nil
+	case RequireAny:
+		if total == 0 {
+			return 0, nil
+		}
+		return 1, nil
+	}
+
+	n, err := strconv.Atoi(require)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("require must be %s, %s or a number greater than 0, not %s", RequireAll, RequireAny, require)
+	}
+	if n > total {
+		return 0, fmt.Errorf("require of %d is more than the %d targets available", n, total)
+	}
+
+	return n, nil
+}
+
+// met returns true when enough targets are ready
+func (r *requirement) met(ready map[string]bool) bool {
+	if r.target != "" {
+		return ready[r.target]
+	}
+
+	count := 0
+	for _, item := range r.items {
+		if item.met(ready) {
+			count++
+		}
+	}
+	return count >= r.required
+}
+
+// unmet returns true when too many targets have failed for the requirement to ever be met
+func (r *requirement) unmet(failed map[string]error) bool {
+	if r.target != "" {
+		_, found := failed[r.target]
+		return found
+	}
+
+	count := 0
+	for _, item := range r.items {
+		if item.unmet(failed) {
+			count++
+		}
+	}
+	return count > len(r.items)-r.required
+}
+
+// contains returns true if target is part of this requirement
+func (r *requirement) contains(target string) bool {
+	if r.target != "" {
+		return r.target == target
+	}
+
+	for _, item := range r.items {
+		if item.contains(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// targets lists the names of all of the targets that are part of this requirement
+func (r *requirement) targets() []string {
+	if r.target != "" {
+		return []string{r.target}
+	}
+
+	var names []string
+	for _, item := range r.items {
+		names = append(names, item.targets()...)
+	}
+	return names
+}
+
+// unmetError explains why the requirement can't be met, using the error from the
+// latest target to fail where possible
+func (r *requirement) unmetError(failed map[string]error, ready map[string]bool, latest string) error {
+	if r.target != "" {
+		return failed[r.target]
+	}
+
+	var cause *requirement
+	count := 0
+	for _, item := range r.items {
+		if item.unmet(failed) && (cause == nil || item.contains(latest)) {
+			cause = item
+		}
+		if item.met(ready) {
+			count++
+		}
+	}
+	if cause == nil {
+		return nil
+	}
+
+	err := cause.unmetError(failed, ready, latest)
+	if r.required == len(r.items) {
+		return err
+	}
+
+	return &RequirementError{
+		Group:    r.name,
+		Required: r.required,
+		Total:    len(r.items),
+		Ready:    count,
+		Err:      err,
+	}
+}
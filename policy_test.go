@@ -0,0 +1,109 @@
+package waitfor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequiredCount(t *testing.T) {
+	tests := []struct {
+		require  string
+		total    int
+		expected int
+	}{
+		{require: "", total: 3, expected: 3},
+		{require: RequireAll, total: 3, expected: 3},
+		{require: RequireAny, total: 3, expected: 1},
+		{require: RequireAny, total: 0, expected: 0},
+		{require: "2", total: 3, expected: 2},
+		{require: "3", total: 3, expected: 3},
+	}
+
+	for _, test := range tests {
+		n, err := requiredCount(test.require, test.total)
+		require.NoError(t, err, "require %s of %d", test.require, test.total)
+		assert.Equal(t, test.expected, n, "require %s of %d", test.require, test.total)
+	}
+}
+
+func TestRequiredCount_failsForInvalidSettings(t *testing.T) {
+	_, err := requiredCount("some", 3)
+	assert.EqualError(t, err, "require must be all, any or a number greater than 0, not some")
+
+	_, err = requiredCount("0", 3)
+	assert.Error(t, err)
+
+	_, err = requiredCount("4", 3)
+	assert.EqualError(t, err, "require of 4 is more than the 3 targets available")
+}
+
+func TestRequirement_quorum(t *testing.T) {
+	r := &requirement{
+		items: []*requirement{
+			{target: "a"},
+			{target: "b"},
+			{target: "c"},
+		},
+		required: 2,
+	}
+
+	assert.False(t, r.met(map[string]bool{"a": true}))
+	assert.True(t, r.met(map[string]bool{"a": true, "c": true}))
+
+	assert.False(t, r.unmet(map[string]error{"a": errors.New("a failed")}))
+	assert.True(t, r.unmet(map[string]error{"a": errors.New("a failed"), "b": errors.New("b failed")}))
+}
+
+func TestRequirement_nestedGroups(t *testing.T) {
+	r := &requirement{
+		items: []*requirement{
+			{target: "db"},
+			{
+				name:     "replicas",
+				items:    []*requirement{{target: "r1"}, {target: "r2"}},
+				required: 1,
+			},
+		},
+		required: 2,
+	}
+
+	assert.False(t, r.met(map[string]bool{"db": true}))
+	assert.False(t, r.met(map[string]bool{"r1": true, "r2": true}))
+	assert.True(t, r.met(map[string]bool{"db": true, "r2": true}))
+	assert.ElementsMatch(t, []string{"db", "r1", "r2"}, r.targets())
+
+	failed := map[string]error{"r1": errors.New("r1 failed")}
+	assert.False(t, r.unmet(failed))
+
+	failed["r2"] = errors.New("r2 failed")
+	require.True(t, r.unmet(failed))
+	assert.EqualError(t, r.unmetError(failed, nil, "r2"), "group replicas needed 1 of 2 to be ready: r2 failed")
+}
+
+func TestRequirement_unmetErrorForAllIsTargetError(t *testing.T) {
+	r := requireAll(map[string]TargetConfig{"a": {}, "b": {}})
+	failed := map[string]error{"b": errors.New("b failed")}
+
+	require.True(t, r.unmet(failed))
+	assert.EqualError(t, r.unmetError(failed, nil, "b"), "b failed")
+}
+
+func TestRequirement_unmetErrorCountsReadyTargets(t *testing.T) {
+	r := &requirement{
+		items:    []*requirement{{target: "a"}, {target: "b"}, {target: "c"}},
+		required: 2,
+	}
+	failed := map[string]error{"b": errors.New("b failed"), "c": errors.New("c failed")}
+
+	err := r.unmetError(failed, map[string]bool{"a": true}, "c")
+
+	var unmet *RequirementError
+	require.True(t, errors.As(err, &unmet))
+	assert.Equal(t, 2, unmet.Required)
+	assert.Equal(t, 3, unmet.Total)
+	assert.Equal(t, 1, unmet.Ready)
+	assert.EqualError(t, err, "needed 2 of 3 to be ready: c failed")
+}
@@ -0,0 +1,168 @@
+package waitfor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// requirementState describes whether a requirement has been decided after a target finishes
+type requirementState int
+
+const (
+	requirementUndecided requirementState = iota
+	requirementMet
+	requirementUnmet
+	requirementStopped
+)
+
+// targetProgress keeps track of which targets have been satisfied so that we can
+// tell when enough are ready and report on what was happening when waiting is cut short
+type targetProgress struct {
+	lock     sync.Mutex
+	required *requirement
+	pending  map[string]bool
+	ready    map[string]bool
+	failures map[string]error
+	met      bool
+	outcomes map[string]Result
+
+	// The state of the targets at the moment that waiting was stopped
+	stopped       bool
+	failed        string
+	readyAtStop   []string
+	pendingAtStop []string
+}
+
+func newTargetProgress(targets map[string]TargetConfig, required *requirement) *targetProgress {
+	p := &targetProgress{
+		required: required,
+		pending:  map[string]bool{},
+		ready:    map[string]bool{},
+		failures: map[string]error{},
+		outcomes: map[string]Result{},
+	}
+	for name, target := range targets {
+		p.pending[name] = true
+		p.outcomes[name] = newResult(name, target)
+	}
+	return p
+}
+
+// finished records the outcome of waiting on a target and decides whether the requirement
+// has been met or can no longer be met. The cancelled flag is true if the target was stopped
+// before it could finish. The error returned explains why the requirement wasn't met.
+func (p *targetProgress) finished(result Result, err error, cancelled bool) (requirementState, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	name := result.Name
+	p.outcomes[name] = result
+
+	if p.met {
+		return requirementMet, nil
+	}
+	if p.stopped {
+		return requirementStopped, err
+	}
+
+	if err == nil {
+		delete(p.pending, name)
+		p.ready[name] = true
+		if p.required.met(p.ready) {
+			p.met = true
+			p.snapshot()
+			return requirementMet, nil
+		}
+		return requirementUndecided, nil
+	}
+
+	if cancelled {
+		p.stop("")
+		return requirementStopped, err
+	}
+
+	delete(p.pending, name)
+	p.failures[name] = err
+	if p.required.unmet(p.failures) {
+		p.stop(name)
+		return requirementUnmet, p.required.unmetError(p.failures, p.ready, name)
+	}
+
+	return requirementUndecided, err
+}
+
+// stop records the state of the targets at the moment that waiting stopped, failed is
+// the target that caused it or empty if waiting was cancelled
+func (p *targetProgress) stop(failed string) {
+	p.stopped = true
+	p.failed = failed
+	p.snapshot()
+}
+
+func (p *targetProgress) snapshot() {
+	p.readyAtStop = nil
+	for n := range p.ready {
+		p.readyAtStop = append(p.readyAtStop, n)
+	}
+	p.pendingAtStop = nil
+	for n := range p.pending {
+		p.pendingAtStop = append(p.pendingAtStop, n)
+	}
+	sort.Strings(p.readyAtStop)
+	sort.Strings(p.pendingAtStop)
+}
+
+// results lists the outcome of every target, sorted by name
+func (p *targetProgress) results() []Result {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var results []Result
+	for _, r := range p.outcomes {
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+// cancelled returns true if waiting was stopped from the outside rather than by a target failing
+func (p *targetProgress) cancelled() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.stopped && p.failed == ""
+}
+
+// notReady lists the targets that were still pending when waiting was stopped
+func (p *targetProgress) notReady() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return append([]string{}, p.pendingAtStop...)
+}
+
+// report logs the state of the targets at the moment that waiting was stopped
+func (p *targetProgress) report(logger StructuredLogger) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.stopped {
+		return
+	}
+
+	if p.failed != "" {
+		logger.Warn(fmt.Sprintf("stopped waiting as %s failed", p.failed), "target", p.failed)
+	} else {
+		logger.Warn("stopped waiting as it was cancelled")
+	}
+	if len(p.readyAtStop) > 0 {
+		logger.Info(fmt.Sprintf("already ready: %s", strings.Join(p.readyAtStop, ", ")), "targets", p.readyAtStop)
+	}
+	if len(p.pendingAtStop) > 0 {
+		logger.Info(fmt.Sprintf("still pending: %s", strings.Join(p.pendingAtStop, ", ")), "targets", p.pendingAtStop)
+	}
+}
@@ -0,0 +1,46 @@
+package waitfor
+
+import (
+	"time"
+)
+
+// Status describes where a target got to while it was being waited on
+type Status string
+
+const (
+	// StatusReady means that the target became available
+	StatusReady Status = "ready"
+	// StatusFailed means that the target didn't become available in time
+	StatusFailed Status = "failed"
+	// StatusCancelled means that waiting on the target was stopped before it finished
+	StatusCancelled Status = "cancelled"
+)
+
+// Result is the outcome of waiting on a single target
+type Result struct {
+	// Name is the name of the target
+	Name string
+	// Type is the kind of target
+	Type string
+	// Target is the location of the target
+	Target string
+	// Status is where the target got to
+	Status Status
+	// Attempts is the number of times that the target was tried
+	Attempts int
+	// Duration is how long was spent waiting on the target, not including waiting on its dependencies
+	Duration time.Duration
+	// LastError is the error from the most recent attempt that failed, or nil if none did
+	LastError error
+	// FirstSuccess is when the first successful attempt was made, or the zero time if there wasn't one
+	FirstSuccess time.Time
+}
+
+func newResult(name string, target TargetConfig) Result {
+	return Result{
+		Name:   name,
+		Type:   target.Type,
+		Target: target.Target,
+		Status: StatusCancelled,
+	}
+}
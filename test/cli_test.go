@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
+	"os"
 	"os/exec"
 	"strings"
 	"sync"
@@ -21,14 +23,18 @@ type stepsData struct {
 
 	statusCode int
 	output     string
-	duration   time.Duration
+
+	responseCode int
+	response     string
+	duration     time.Duration
 
 	servers   []*http.Server
 	serverWG  sync.WaitGroup
 	listening bool
 
-	requests     []string
-	requestsLock sync.Mutex
+	requests       []string
+	authorizations []string
+	requestsLock   sync.Mutex
 
 	connections     []string
 	connectionsLock sync.Mutex
@@ -44,10 +50,13 @@ func (s *stepsData) Reset() {
 
 	s.statusCode = 0
 	s.output = ""
+	s.responseCode = 0
+	s.response = ""
 
 	s.requestsLock.Lock()
 	defer s.requestsLock.Unlock()
 	s.requests = []string{}
+	s.authorizations = []string{}
 }
 
 func (s *stepsData) StopListening() {
@@ -87,6 +96,17 @@ func (s *stepsData) addRequest(r *http.Request) {
 		s.requests,
 		fmt.Sprintf("%s %s %s", r.Host, r.Method, r.URL),
 	)
+	s.authorizations = append(s.authorizations, r.Header.Get("Authorization"))
+}
+
+func (s *stepsData) getAuthorizations() []string {
+	s.requestsLock.Lock()
+	defer s.requestsLock.Unlock()
+
+	var authorizations []string
+	authorizations = append(authorizations, s.authorizations...)
+
+	return authorizations
 }
 
 func (s *stepsData) getRequests() []string {
@@ -137,6 +157,87 @@ func (s *stepsData) iRunWaitforWithParameters(params string) error {
 	return nil
 }
 
+func (s *stepsData) iRunWaitforWithParametersAndInterruptItAfter(params, after string) error {
+	delay, err := time.ParseDuration(after)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("../wait-for", strings.Split(params, " ")...)
+
+	var b bytes.Buffer
+	cmd.Stderr = &b
+	cmd.Stdout = &b
+
+	start := time.Now()
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	timer := time.AfterFunc(delay, func() {
+		_ = cmd.Process.Signal(os.Interrupt)
+	})
+	defer timer.Stop()
+
+	_ = cmd.Wait()
+	s.output = b.String()
+	s.statusCode = cmd.ProcessState.ExitCode()
+
+	s.duration = time.Since(start)
+
+	return nil
+}
+
+func (s *stepsData) iRunWaitforWithParametersAndRequestAfter(params, url, after string) error {
+	delay, err := time.ParseDuration(after)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("../wait-for", strings.Split(params, " ")...)
+
+	var b bytes.Buffer
+	cmd.Stderr = &b
+	cmd.Stdout = &b
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	defer func() {
+		_ = cmd.Process.Signal(os.Interrupt)
+		_ = cmd.Wait()
+		s.output = b.String()
+		s.statusCode = cmd.ProcessState.ExitCode()
+	}()
+
+	time.Sleep(delay)
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	s.responseCode = resp.StatusCode
+	s.response = string(body)
+
+	return nil
+}
+
+func (s *stepsData) theResponseContains(expected string) error {
+	assert.Contains(s, s.response, expected)
+	return s.assertError
+}
+
+func (s *stepsData) theResponseCodeIs(code int) error {
+	assert.Equal(s, code, s.responseCode)
+	return s.assertError
+}
+
 func (s *stepsData) theOutputContains(expected string) error {
 	assert.Contains(s, s.output, expected)
 	return s.assertError
@@ -147,6 +248,16 @@ func (s *stepsData) theOutputDoesNotContain(expected string) error {
 	return s.assertError
 }
 
+func (s *stepsData) theFileContains(path, expected string) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	assert.Contains(s, string(contents), expected)
+	return s.assertError
+}
+
 func (s *stepsData) theTimeTakenIsMoreThan(expected string) error {
 	duration, err := time.ParseDuration(expected)
 	if err != nil {
@@ -177,11 +288,26 @@ func (s *stepsData) waitforExitsWithoutError() error {
 	return s.assertError
 }
 
+func (s *stepsData) waitforExitsWithCode(code int) error {
+	assert.Equal(s, code, s.statusCode)
+	return s.assertError
+}
+
 func (s *stepsData) iCanSeeThatAnHTTPRequestWasMadeFor(r string) error {
 	assert.Contains(s, s.getRequests(), r)
 	return s.assertError
 }
 
+func (s *stepsData) noHTTPRequestsWereMade() error {
+	assert.Empty(s, s.getRequests())
+	return s.assertError
+}
+
+func (s *stepsData) iCanSeeThatAnHTTPRequestWasMadeWithAuthorization(a string) error {
+	assert.Contains(s, s.getAuthorizations(), a)
+	return s.assertError
+}
+
 func (s *stepsData) iCanSeeAConnectionEvent(e string) error {
 	assert.Contains(s, s.getConnections(), e)
 	return s.assertError
@@ -197,6 +323,27 @@ func (s *stepsData) iHaveAnHTTPServerOnPortWithStatus(port, status int) error {
 	return nil
 }
 
+func (s *stepsData) iHaveAnHTTPServerOnPortWithBody(port int, body string) error {
+	recordRequest := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.addRequest(r)
+		_, _ = w.Write([]byte(body))
+	})
+	s.startListener(fmt.Sprintf(":%d", port), recordRequest)
+	time.Sleep(time.Millisecond * 250)
+	return nil
+}
+
+func (s *stepsData) iHaveAnHTTPServerOnPortWithHeader(port int, name, value string) error {
+	recordRequest := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.addRequest(r)
+		w.Header().Set(name, value)
+		w.WriteHeader(http.StatusOK)
+	})
+	s.startListener(fmt.Sprintf(":%d", port), recordRequest)
+	time.Sleep(time.Millisecond * 250)
+	return nil
+}
+
 func (s *stepsData) listeningServerStatusThenStatus(port, startCode int, duration string, endCode int) error {
 	waitTime, err := time.ParseDuration(duration)
 	if err != nil {
@@ -262,12 +409,22 @@ func InitializeScenario(ctx *godog.ScenarioContext) {
 		}
 	})
 	ctx.Step(`^I run wait-for with parameters "([^"]*)"$`, data.iRunWaitforWithParameters)
+	ctx.Step(`^I run wait-for with parameters "([^"]*)" and interrupt it after "([^"]*)"$`, data.iRunWaitforWithParametersAndInterruptItAfter)
 	ctx.Step(`^wait-for exits without error$`, data.waitforExitsWithoutError)
 	ctx.Step(`^wait-for exits with an error$`, data.waitforExitsWithAnError)
-	ctx.Step(`^the output contains "([^"]*)"$`, data.theOutputContains)
-	ctx.Step(`^the output does not contain "([^"]*)"$`, data.theOutputDoesNotContain)
+	ctx.Step(`^wait-for exits with code (\d+)$`, data.waitforExitsWithCode)
+	ctx.Step(`^the output contains "(.*)"$`, data.theOutputContains)
+	ctx.Step(`^the output does not contain "(.*)"$`, data.theOutputDoesNotContain)
+	ctx.Step(`^the file "([^"]*)" contains "(.*)"$`, data.theFileContains)
+	ctx.Step(`^I run wait-for with parameters "([^"]*)" and request "([^"]*)" after "([^"]*)"$`, data.iRunWaitforWithParametersAndRequestAfter)
+	ctx.Step(`^the response contains "(.*)"$`, data.theResponseContains)
+	ctx.Step(`^the response code is (\d+)$`, data.theResponseCodeIs)
 	ctx.Step(`^I can see that an HTTP request was made for "([^"]*)"$`, data.iCanSeeThatAnHTTPRequestWasMadeFor)
+	ctx.Step(`^no HTTP requests were made$`, data.noHTTPRequestsWereMade)
+	ctx.Step(`^I can see that an HTTP request was made with authorization "([^"]*)"$`, data.iCanSeeThatAnHTTPRequestWasMadeWithAuthorization)
 	ctx.Step(`^I have an HTTP server running on port (\d+) that responds with (\d+)$`, data.iHaveAnHTTPServerOnPortWithStatus)
+	ctx.Step(`^I have an HTTP server running on port (\d+) that responds with body "(.*)"$`, data.iHaveAnHTTPServerOnPortWithBody)
+	ctx.Step(`^I have an HTTP server running on port (\d+) that responds with header "([^"]*)" set to "([^"]*)"$`, data.iHaveAnHTTPServerOnPortWithHeader)
 	ctx.Step(`^the time taken is more than "([^"]*)"`, data.theTimeTakenIsMoreThan)
 	ctx.Step(`^the time taken is less than "([^"]*)"$`, data.theTimeTakenIsLessThan)
 	ctx.Step(`^I have an HTTP server running on port (\d+) that responds with (\d+) for "([^"]*)" then responds with (\d+)$`, data.listeningServerStatusThenStatus)
@@ -12,6 +12,7 @@ Feature: Configuration from CLI
     Then I can see that an HTTP request was made for "localhost GET /health"
     And the output contains "started waiting for http://localhost/health"
     And the output contains "finished waiting for http://localhost/health"
+    And the output contains "http://localhost/health: ready after 1 attempts"
     And wait-for exits without error
 
   Scenario: Waits on a TCP connection
@@ -39,7 +40,8 @@ Feature: Configuration from CLI
     Then I can see that an HTTP request was made for "localhost GET /health"
     And the output contains "error while waiting for http://localhost/health"
     And the output contains "timed out waiting for http://localhost/health"
-    And wait-for exits with an error
+    And the output contains "http://localhost/health: failed after"
+    And wait-for exits with code 4
 
   Scenario: Fails when HTTP listener response with 400 error
     Given I have an HTTP server running on port 80 that responds with 400
@@ -47,6 +49,42 @@ Feature: Configuration from CLI
     Then I can see that an HTTP request was made for "localhost GET /health"
     And wait-for exits with an error
 
+  Scenario: Uses the HTTP method and headers given
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-http-method HEAD -http-header Host:api.internal http://localhost/health"
+    Then I can see that an HTTP request was made for "api.internal HEAD /health"
+    And wait-for exits without error
+
+  Scenario: Sends a body with HTTP requests
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-http-method POST -http-body ping http://localhost/health"
+    Then I can see that an HTTP request was made for "localhost POST /health"
+    And wait-for exits without error
+
+  Scenario: Waits until the HTTP response body is as expected
+    Given I have an HTTP server running on port 80 that responds with body "{"status":"ok","checks":[{"name":"db","status":"ok"}]}"
+    When I run wait-for with parameters "-expect-body status -expect-json checks.0.status=ok http://localhost/health"
+    Then I can see that an HTTP request was made for "localhost GET /health"
+    And wait-for exits without error
+
+  Scenario: Reports when the HTTP response body isn't as expected
+    Given I have an HTTP server running on port 80 that responds with body "{"status":"starting"}"
+    When I run wait-for with parameters "-timeout 2s -expect-json status=ok http://localhost/health"
+    Then the output contains "status is "starting" in the body, expected "ok""
+    And wait-for exits with code 4
+
+  Scenario: Waits until the HTTP response has the expected headers
+    Given I have an HTTP server running on port 80 that responds with header "X-App-Version" set to "1.5.0"
+    When I run wait-for with parameters "-expect-header X-App-Version -expect-header X-App-Version~^1\.5\. http://localhost/health"
+    Then I can see that an HTTP request was made for "localhost GET /health"
+    And wait-for exits without error
+
+  Scenario: Reports when the HTTP response headers aren't as expected
+    Given I have an HTTP server running on port 80 that responds with header "X-App-Version" set to "1.4.2"
+    When I run wait-for with parameters "-timeout 2s -expect-header X-App-Version=1.5.0 http://localhost/health"
+    Then the output contains "header X-App-Version is "1.4.2", expected "1.5.0""
+    And wait-for exits with code 4
+
   Scenario: Times out if it can't connect to a service
     When I run wait-for with parameters "http://non-existent/health"
     Then wait-for exits with an error
@@ -76,6 +114,158 @@ Feature: Configuration from CLI
     And the time taken is less than "5s"
     And the output contains "timed out waiting for http://non-existent/health"
 
+  Scenario: Interval is configurable
+    Given I have an HTTP server running on port 80 that responds with 500 for "1s" then responds with 200
+    When I run wait-for with parameters "-interval 3s http://localhost/health"
+    Then wait-for exits without error
+    And the output contains "finished waiting for http://localhost/health"
+    And the time taken is more than "3s"
+
+  Scenario: Fails when backoff is unknown
+    When I run wait-for with parameters "-backoff sometimes http://localhost/health"
+    Then wait-for exits with an error
+    And the output contains "unknown backoff sometimes"
+
+  Scenario: Waits for consecutive successes
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-success-threshold 3 -interval 100ms http://localhost/health"
+    Then wait-for exits without error
+    And the output contains "got 1 of 3 consecutive successes for http://localhost/health"
+    And the output contains "got 2 of 3 consecutive successes for http://localhost/health"
+    And the output contains "finished waiting for http://localhost/health"
+
+  Scenario: Waits for a service to go away
+    When I run wait-for with parameters "!tcp:localhost:8099"
+    Then wait-for exits without error
+    And the output contains "finished waiting for !tcp:localhost:8099"
+
+  Scenario: Fails when a service doesn't go away
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-down -timeout 2s http://localhost/health"
+    Then wait-for exits with an error
+    And the output contains "http://localhost/health is still available"
+
+  Scenario: Waits for any of the services
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-any -timeout 20s http://localhost/health http://non-existent/health"
+    Then wait-for exits without error
+    And the time taken is less than "5s"
+    And the output contains "finished waiting for http://localhost/health"
+    And the output contains "stopped waiting for http://non-existent/health as enough targets are ready"
+
+  Scenario: Fails when a quorum of services isn't available
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-quorum 2 -timeout 2s http://localhost/health http://non-existent/health http://also-non-existent/health"
+    Then wait-for exits with code 5
+    And the output contains "needed 2 of 3 to be ready"
+
+  Scenario: Runs a command once services are available
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-env http://localhost/health -- env"
+    Then wait-for exits without error
+    And the output contains "running env"
+    And the output contains "WAIT_FOR_HTTP_LOCALHOST_HEALTH_STATUS=ready"
+    And the output contains "WAIT_FOR_READY=http://localhost/health"
+
+  Scenario: Refuses to pass the same environment variables for two targets
+    When I run wait-for with parameters "-env http://localhost/health http://localhost/health/ -- env"
+    Then wait-for exits with code 2
+    And the output contains "would both set the WAIT_FOR_HTTP_LOCALHOST_HEALTH_* environment variables"
+
+  Scenario: Does not run a command when services are not available
+    When I run wait-for with parameters "-timeout 1s http://non-existent/health -- echo started"
+    Then wait-for exits with code 4
+    And the output does not contain "running echo started"
+
+  Scenario: Runs a command on timeout when asked to
+    When I run wait-for with parameters "-run-on-timeout -timeout 1s http://non-existent/health -- echo started"
+    Then wait-for exits without error
+    And the output contains "timed out waiting for http://non-existent/health"
+    And the output contains "running echo started"
+
+  Scenario: Reports progress when interrupted
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-quiet -timeout 20s http://localhost/health http://non-existent/health" and interrupt it after "2s"
+    Then wait-for exits with code 130
+    And the time taken is less than "5s"
+    And the output contains "received interrupt, stopping"
+    And the output contains "http://localhost/health: ready after"
+    And the output contains "http://non-existent/health: cancelled after"
+    And the output contains "last error:"
+
+  Scenario: Writes results as JSON
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-output json http://localhost/health"
+    Then wait-for exits without error
+    And the output contains ""record": "attempt""
+    And the output contains ""record": "result""
+    And the output contains ""status": "ready""
+    And the output does not contain "started waiting for"
+
+  Scenario: Streams events as JSON lines
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-output ndjson http://localhost/health"
+    Then wait-for exits without error
+    And the output contains "{"record":"status","
+    And the output contains ""name":"http://localhost/health","status":"waiting"}"
+    And the output contains "{"record":"result","name":"http://localhost/health","type":"http","
+
+  Scenario: Writes reports for CI
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-timeout 1s -report junit=/tmp/wait-for-report.xml -report markdown=/tmp/wait-for-report.md http://localhost/health http://non-existent/health"
+    Then wait-for exits with code 4
+    And the file "/tmp/wait-for-report.xml" contains "<testsuite name="wait-for" tests="2" failures="1""
+    And the file "/tmp/wait-for-report.xml" contains "<testcase name="http://localhost/health" classname="wait-for.http""
+    And the file "/tmp/wait-for-report.xml" contains "<failure message="http://non-existent/health failed after"
+    And the file "/tmp/wait-for-report.md" contains "| http://localhost/health | http | ready | 1 |"
+
+  Scenario: Writes structured logs
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-log-format json -log-level debug http://localhost/health"
+    Then wait-for exits without error
+    And the output contains ""level":"DEBUG","msg":"attempt 1 for http://localhost/health took"
+    And the output contains ""msg":"finished waiting for http://localhost/health","target":"http://localhost/health","type":"http","attempts":1"
+
+  Scenario: Leaves out logs below the log level
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-log-format logfmt -log-level warn http://localhost/health"
+    Then wait-for exits without error
+    And the output does not contain "finished waiting for http://localhost/health"
+
+  Scenario: Fails with a usage error when flags conflict
+    When I run wait-for with parameters "-any -quorum 2 http://localhost/health"
+    Then wait-for exits with code 2
+    And the output contains "-any and -quorum can't be used together"
+
+  Scenario: Fails with a config error when a target can't be understood
+    When I run wait-for with parameters "not-a-target"
+    Then wait-for exits with code 3
+    And the output contains "unable to understand target not-a-target"
+
+  Scenario: Fails with a config error when the TLS settings don't make sense
+    When I run wait-for with parameters "-tls-min-version 1.9 https://localhost/health"
+    Then wait-for exits with code 3
+    And the output contains "unknown TLS version 1.9"
+
+  Scenario: Fails when a HTTP service doesn't speak TLS
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-timeout 2s -insecure-skip-verify https://localhost:80/health"
+    Then wait-for exits with code 4
+    And the output contains "could not connect to https://localhost:80/health"
+
+  Scenario: TLS settings on the command line don't change TCP targets
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-timeout 2s -insecure-skip-verify -tls-min-version 1.2 tcp:localhost:80"
+    Then the output contains "finished waiting for tcp:localhost:80"
+    And wait-for exits without error
+
+  Scenario: Global timeout stops all targets
+    When I run wait-for with parameters "-timeout 20s -global-timeout 2s http://non-existent/health"
+    Then wait-for exits with code 4
+    And the time taken is less than "5s"
+    And the output contains "global timeout of 2s exceeded"
+    And the output contains "still pending: http://non-existent/health"
+
   Scenario: Status is configurable
     Given I have an HTTP server running on port 80 that responds with 400
     When I run wait-for with parameters "-status 400 http://localhost/health"
@@ -86,15 +276,15 @@ Feature: Configuration from CLI
   Scenario: Fails when status is not a valid regex
     Given I have an HTTP server running on port 80 that responds with 200
     When I run wait-for with parameters "-status [4-0]{3} http://localhost/health"
-    Then I can see that an HTTP request was made for "localhost GET /health"
-    And the output contains " error while waiting for http://localhost/health:  invalid Regular Expression"
-    And wait-for exits with an error
+    Then wait-for exits with code 3
+    And no HTTP requests were made
+    And the output contains "invalid status pattern [4-0]{3}"
 
   Scenario: Fails when status doesn't match response
     Given I have an HTTP server running on port 80 that responds with 200
     When I run wait-for with parameters "-status 500 http://localhost/health"
     Then I can see that an HTTP request was made for "localhost GET /health"
-    And the output contains " error while waiting for http://localhost/health:  200 status Code and 500 regex didn't match"
+    And the output contains "error while waiting for http://localhost/health: 200 status Code and 500 regex didn't match"
     And wait-for exits with an error
 
 
@@ -116,3 +306,29 @@ Feature: Configuration from CLI
     And the output contains "timed out waiting for http://localhost/health"
       # We still want to know what failed!
 
+
+  Scenario: Watches services and serves metrics
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "watch -listen 127.0.0.1:9090 -interval 100ms http://localhost/health tcp:localhost:8099" and request "http://127.0.0.1:9090/metrics" after "1s"
+    Then the response code is 200
+    And the response contains "wait_for_target_up{target="http://localhost/health",type="http"} 1"
+    And the response contains "wait_for_target_up{target="tcp:localhost:8099",type="tcp"} 0"
+    And the response contains "wait_for_probe_duration_seconds_count{target="http://localhost/health",type="http"}"
+    And the response contains "wait_for_target_last_success_timestamp_seconds{target="http://localhost/health",type="http"}"
+    And wait-for exits without error
+
+  Scenario: Serves readiness of services
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "serve -listen 127.0.0.1:9091 -interval 100ms http://localhost/health tcp:localhost:8099" and request "http://127.0.0.1:9091/status" after "1s"
+    Then the response code is 503
+    And the response contains ""ready": false"
+    And the response contains ""name": "http://localhost/health""
+    And the response contains ""consecutive_failures": 0"
+    And the response contains ""error": "could not connect to tcp:localhost:8099"
+    And wait-for exits without error
+
+  Scenario: Serves ready once enough services are available
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "serve -any -listen 127.0.0.1:9091 -interval 100ms http://localhost/health tcp:localhost:8099" and request "http://127.0.0.1:9091/ready" after "1s"
+    Then the response code is 200
+    And wait-for exits without error
@@ -31,3 +31,51 @@ Feature: Configuration from files
     And I can see that an HTTP request was made for "localhost:81 GET /health"
     And the time taken is less than "5s"
 
+  Scenario: Waits on dependencies before a target
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-config fixtures/wait-for.yaml dependent-connection"
+    Then wait-for exits without error
+    And the output contains "dependent-connection is waiting for tcp-connection"
+    And the output contains "finished waiting for tcp-connection"
+    And the output contains "finished waiting for dependent-connection"
+
+  Scenario: Reports targets waiting on dependencies from the start
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-config fixtures/wait-for.yaml -output ndjson dependent-connection"
+    Then wait-for exits without error
+    And the output contains ""name":"dependent-connection","status":"pending"}"
+    And the output contains ""name":"tcp-connection","status":"waiting"}"
+    And the output contains ""name":"dependent-connection","status":"waiting"}"
+
+  Scenario: Sends credentials read from a file
+    Given I have an HTTP server running on port 80 that responds with 200
+    When I run wait-for with parameters "-config fixtures/wait-for.yaml authenticated-connection"
+    Then I can see that an HTTP request was made with authorization "Bearer fixture-token"
+    And the output does not contain "fixture-token"
+    And wait-for exits without error
+
+  Scenario: Uses the defaults from the config file for targets on the command line
+    When I run wait-for with parameters "-config fixtures/defaults.yaml tcp:localhost:81"
+    Then wait-for exits with code 4
+    And the output contains "tcp:localhost:81: failed after 3 attempts"
+
+  Scenario: Uses the defaults from the config file for its own targets
+    When I run wait-for with parameters "-config fixtures/defaults.yaml closed-connection"
+    Then wait-for exits with code 4
+    And the output contains "closed-connection: failed after 2 attempts"
+
+  Scenario: Defaults on the command line replace the ones in the config file
+    When I run wait-for with parameters "-config fixtures/defaults.yaml -interval 200ms -backoff constant closed-connection"
+    Then wait-for exits with code 4
+    And the output contains "closed-connection: failed after"
+    And the output does not contain "closed-connection: failed after 2 attempts"
+
+  Scenario: Waits for targets in the config file to go away
+    When I run wait-for with parameters "-config fixtures/defaults.yaml -down closed-connection"
+    Then wait-for exits without error
+    And the output contains "finished waiting for closed-connection"
+
+  Scenario: Fails with a config error when the config file is missing
+    When I run wait-for with parameters "-config fixtures/missing.yaml http-connection"
+    Then wait-for exits with code 3
+    And the output contains "unable to open config file"
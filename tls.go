@@ -0,0 +1,87 @@
+package waitfor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// supportedTLSVersions maps the names that can be used for MinVersion to TLS versions
+var supportedTLSVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig describes how to make TLS connections to a target. HTTP targets use it for https
+// URLs, while TCP and gRPC targets only use TLS when it is set.
+type TLSConfig struct {
+	// CAFile is a PEM bundle of the certificate authorities to trust instead of the system ones
+	CAFile string `yaml:"ca-file"`
+	// CertFile is the PEM certificate to present to the target, it needs KeyFile too
+	CertFile string `yaml:"cert-file"`
+	// KeyFile is the PEM private key for CertFile
+	KeyFile string `yaml:"key-file"`
+	// ServerName is the name expected in the target's certificate if it isn't the host connected to
+	ServerName string `yaml:"server-name"`
+	// MinVersion is the oldest version of TLS allowed, one of 1.0, 1.1, 1.2 or 1.3
+	MinVersion string `yaml:"min-version"`
+	// InsecureSkipVerify accepts any certificate from the target without checking it
+	InsecureSkipVerify bool `yaml:"insecure-skip-verify"`
+}
+
+// check makes sure that the settings make sense without reading any of the files
+func (c *TLSConfig) check() error {
+	if c == nil {
+		return nil
+	}
+	if _, found := supportedTLSVersions[c.MinVersion]; c.MinVersion != "" && !found {
+		return fmt.Errorf("unknown TLS version %s", c.MinVersion)
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("TLS cert-file and key-file must be set together")
+	}
+	return nil
+}
+
+// newTLSConfig creates the TLS configuration used to connect to a target, reading the files
+// each time so that certificates that are renewed are picked up. It returns nil if there are
+// no TLS settings.
+func newTLSConfig(c *TLSConfig) (*tls.Config, error) {
+	if c == nil {
+		return nil, nil
+	}
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		MinVersion:         supportedTLSVersions[c.MinVersion],
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", c.CAFile)
+		}
+	}
+
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
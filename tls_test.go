@@ -0,0 +1,278 @@
+package waitfor
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// testCA is a certificate authority used to create certificates for tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "wait-for test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	ca := &testCA{cert: cert, key: key, dir: t.TempDir()}
+	ca.write(t, "ca.pem", "CERTIFICATE", der)
+	return ca
+}
+
+func (ca *testCA) path(name string) string {
+	return filepath.Join(ca.dir, name)
+}
+
+func (ca *testCA) write(t *testing.T, name, blockType string, der []byte) {
+	contents := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(ca.path(name), contents, 0o600))
+}
+
+// issue creates a certificate for localhost signed by the CA, writing it to name.pem and
+// name-key.pem
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost", "service.internal"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	ca.write(t, name+".pem", "CERTIFICATE", der)
+	ca.write(t, name+"-key.pem", "EC PRIVATE KEY", keyDER)
+
+	cert, err := tls.LoadX509KeyPair(ca.path(name+".pem"), ca.path(name+"-key.pem"))
+	require.NoError(t, err)
+	return cert
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// tlsServer starts an HTTPS server with a certificate from ca using config
+func tlsServer(t *testing.T, ca *testCA, config *tls.Config) *httptest.Server {
+	config.Certificates = []tls.Certificate{ca.issue(t, "server", x509.ExtKeyUsageServerAuth)}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = config
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewTLSConfig_nilWithoutSettings(t *testing.T) {
+	config, err := newTLSConfig(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, config)
+}
+
+func TestNewTLSConfig_usesSettings(t *testing.T) {
+	ca := newTestCA(t)
+	ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+
+	config, err := newTLSConfig(&TLSConfig{
+		CAFile:             ca.path("ca.pem"),
+		CertFile:           ca.path("client.pem"),
+		KeyFile:            ca.path("client-key.pem"),
+		ServerName:         "service.internal",
+		MinVersion:         "1.3",
+		InsecureSkipVerify: true,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "service.internal", config.ServerName)
+	assert.Equal(t, uint16(tls.VersionTLS13), config.MinVersion)
+	assert.True(t, config.InsecureSkipVerify)
+	assert.Len(t, config.Certificates, 1)
+	assert.True(t, config.RootCAs.Equal(ca.pool()))
+}
+
+func TestNewTLSConfig_failsForBadSettings(t *testing.T) {
+	ca := newTestCA(t)
+	ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+
+	tests := map[string]struct {
+		config   TLSConfig
+		expected string
+	}{
+		"unknown version":  {config: TLSConfig{MinVersion: "1.4"}, expected: "unknown TLS version 1.4"},
+		"cert without key": {config: TLSConfig{CertFile: ca.path("client.pem")}, expected: "TLS cert-file and key-file must be set together"},
+		"key without cert": {config: TLSConfig{KeyFile: ca.path("client-key.pem")}, expected: "TLS cert-file and key-file must be set together"},
+		"missing CA file":  {config: TLSConfig{CAFile: ca.path("missing.pem")}, expected: "unable to read CA file"},
+		"CA file not PEM":  {config: TLSConfig{CAFile: ca.path("client-key.pem")}, expected: "no certificates found in CA file"},
+		"missing cert":     {config: TLSConfig{CertFile: ca.path("missing.pem"), KeyFile: ca.path("client-key.pem")}, expected: "unable to load client certificate"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := newTLSConfig(&test.config)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.expected)
+		})
+	}
+}
+
+func TestHTTPWaiter_usesCAFile(t *testing.T) {
+	ca := newTestCA(t)
+	server := tlsServer(t, ca, &tls.Config{})
+
+	target := &TargetConfig{Target: server.URL, StatusPattern: DefaultStatusPattern}
+	err := HTTPWaiterContext(context.Background(), "server", target)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "certificate")
+
+	target.TLS = &TLSConfig{CAFile: ca.path("ca.pem")}
+	assert.NoError(t, HTTPWaiterContext(context.Background(), "server", target))
+}
+
+func TestHTTPWaiter_canSkipVerification(t *testing.T) {
+	ca := newTestCA(t)
+	server := tlsServer(t, ca, &tls.Config{})
+
+	err := HTTPWaiterContext(context.Background(), "server", &TargetConfig{
+		Target:        server.URL,
+		StatusPattern: DefaultStatusPattern,
+		TLS:           &TLSConfig{InsecureSkipVerify: true},
+	})
+	assert.NoError(t, err)
+}
+
+func TestHTTPWaiter_checksServerName(t *testing.T) {
+	ca := newTestCA(t)
+	server := tlsServer(t, ca, &tls.Config{})
+
+	target := &TargetConfig{
+		Target:        server.URL,
+		StatusPattern: DefaultStatusPattern,
+		TLS:           &TLSConfig{CAFile: ca.path("ca.pem"), ServerName: "service.internal"},
+	}
+	assert.NoError(t, HTTPWaiterContext(context.Background(), "server", target))
+
+	target.TLS.ServerName = "other.internal"
+	err := HTTPWaiterContext(context.Background(), "server", target)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "other.internal")
+}
+
+func TestHTTPWaiter_presentsClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+	server := tlsServer(t, ca, &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  ca.pool(),
+	})
+
+	target := &TargetConfig{
+		Target:        server.URL,
+		StatusPattern: DefaultStatusPattern,
+		TLS:           &TLSConfig{CAFile: ca.path("ca.pem")},
+	}
+	assert.Error(t, HTTPWaiterContext(context.Background(), "server", target))
+
+	target.TLS.CertFile = ca.path("client.pem")
+	target.TLS.KeyFile = ca.path("client-key.pem")
+	assert.NoError(t, HTTPWaiterContext(context.Background(), "server", target))
+}
+
+func TestHTTPWaiter_usesMinVersion(t *testing.T) {
+	ca := newTestCA(t)
+	server := tlsServer(t, ca, &tls.Config{MaxVersion: tls.VersionTLS12})
+
+	target := &TargetConfig{
+		Target:        server.URL,
+		StatusPattern: DefaultStatusPattern,
+		TLS:           &TLSConfig{CAFile: ca.path("ca.pem"), MinVersion: "1.2"},
+	}
+	assert.NoError(t, HTTPWaiterContext(context.Background(), "server", target))
+
+	target.TLS.MinVersion = "1.3"
+	assert.Error(t, HTTPWaiterContext(context.Background(), "server", target))
+}
+
+func TestTCPWaiter_usesTLSWhenSet(t *testing.T) {
+	ca := newTestCA(t)
+	server := tlsServer(t, ca, &tls.Config{})
+	addr := strings.TrimPrefix(server.URL, "https://")
+
+	target := &TargetConfig{Target: addr, TLS: &TLSConfig{CAFile: ca.path("ca.pem")}}
+	assert.NoError(t, TCPWaiterContext(context.Background(), "server", target))
+
+	target.TLS = &TLSConfig{}
+	err := TCPWaiterContext(context.Background(), "server", target)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "certificate")
+}
+
+func TestGRPCWaiter_usesTLSWhenSet(t *testing.T) {
+	ca := newTestCA(t)
+	cert := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{cert}})))
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = GRPCWaiterContext(ctx, "server", &TargetConfig{
+		Target:  lis.Addr().String(),
+		Timeout: DefaultTimeout,
+		TLS:     &TLSConfig{CAFile: ca.path("ca.pem")},
+	})
+	assert.NoError(t, err)
+}
@@ -2,15 +2,16 @@ package waitfor
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
-	"net/http"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 
 	"golang.org/x/sync/errgroup"
@@ -23,6 +24,11 @@ type Waiter interface {
 	Wait(name string, target *TargetConfig) error
 }
 
+// ContextWaiter is a Waiter that stops waiting when the context it is given is cancelled.
+type ContextWaiter interface {
+	WaitContext(ctx context.Context, name string, target *TargetConfig) error
+}
+
 // WaiterFunc is used to implement waiting for a specific type of target.
 // The name is used in the error and target is the actual destination being tested.
 type WaiterFunc func(name string, target *TargetConfig) error
@@ -31,162 +37,563 @@ func (w WaiterFunc) Wait(name string, target *TargetConfig) error {
 	return w(name, target)
 }
 
-type Logger func(string, ...interface{})
+// WaitContext adapts a WaiterFunc so that it can be used as a ContextWaiter. The function
+// itself can't be interrupted, so cancelling ctx only stops the caller waiting for its result.
+func (w WaiterFunc) WaitContext(ctx context.Context, name string, target *TargetConfig) error {
+	return waitWithContext(ctx, func() error {
+		return w(name, target)
+	})
+}
+
+// ContextWaiterFunc is used to implement waiting for a specific type of target that can be
+// cancelled using ctx.
+type ContextWaiterFunc func(ctx context.Context, name string, target *TargetConfig) error
+
+func (w ContextWaiterFunc) Wait(name string, target *TargetConfig) error {
+	return w(context.Background(), name, target)
+}
+
+func (w ContextWaiterFunc) WaitContext(ctx context.Context, name string, target *TargetConfig) error {
+	return w(ctx, name, target)
+}
+
+// AsContextWaiter returns w as a ContextWaiter, adapting it if it doesn't support cancellation
+func AsContextWaiter(w Waiter) ContextWaiter {
+	if cw, ok := w.(ContextWaiter); ok {
+		return cw
+	}
+	return WaiterFunc(w.Wait)
+}
+
+// waitWithContext runs f in the background and returns its result, or the context error if
+// ctx is done before f returns
+func waitWithContext(ctx context.Context, f func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- f()
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sleepContext pauses for d, returning early with the context error if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
 
-// NullLogger can be used in place of a real logging function
-var NullLogger = func(f string, a ...interface{}) {}
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
 // SupportedWaiters is a mapping of known protocol names to waiter implementations
 var SupportedWaiters map[string]Waiter
 
 // WaitOn implements waiting for many targets, using the location of config file provided with named targets to wait until
-// all of those targets are responding as expected
+// all of those targets are responding as expected. Setting Require in the config allows waiting for any, or a number, of
+// the targets instead.
 func WaitOn(config *Config, logger Logger, targets []string, waiters map[string]Waiter) error {
+	return WaitOnContext(context.Background(), config, logger, targets, waiters)
+}
+
+// WaitOnContext is the same as WaitOn but gives up on all of the targets as soon as ctx is cancelled
+func WaitOnContext(ctx context.Context, config *Config, logger Logger, targets []string, waiters map[string]Waiter) error {
+	return WaitOnStructured(ctx, config, logger, targets, waiters)
+}
+
+// WaitOnStructured is the same as WaitOnContext but logs with levels and fields to logger,
+// such as a *slog.Logger
+func WaitOnStructured(ctx context.Context, config *Config, logger StructuredLogger, targets []string, waiters map[string]Waiter) error {
+	_, err := WaitOnResults(ctx, config, logger, targets, waiters)
+	return err
+}
+
+// WaitOnResults is the same as WaitOnContext but also returns the result of waiting on each
+// target, sorted by name. There will be no results if waiting couldn't start. Any handlers
+// are told about each attempt and change of status as it happens.
+func WaitOnResults(ctx context.Context, config *Config, logger StructuredLogger, targets []string, waiters map[string]Waiter, handlers ...EventHandler) ([]Result, error) {
+	required, filtered, err := resolveTargets(config, targets)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, target := range targets {
-		if !config.GotTarget(target) {
-			err := config.AddFromString(target)
+	if config.GlobalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.GlobalTimeout)
+		defer cancel()
+	}
+
+	results, err := waitOnRequirement(ctx, logger, filtered.Targets, waiters, required, eventHandlers(handlers))
+	if err != nil && config.GlobalTimeout > 0 && ctx.Err() == context.DeadlineExceeded {
+		return results, &TimeoutError{Timeout: config.GlobalTimeout, Err: err}
+	}
+
+	return results, err
+}
+
+// resolveTargets works out what is required for the targets and groups named, adding any
+// targets that aren't already in config
+func resolveTargets(config *Config, names []string) (*requirement, *Config, error) {
+	for _, name := range names {
+		if !config.GotTarget(name) && !config.GotGroup(name) {
+			err := config.AddFromString(name)
 			if err != nil {
-				return err
+				return nil, nil, err
 			}
 		}
 	}
-	filtered := config.Filter(targets)
-	err := waitOnTargets(logger, filtered.Targets, waiters)
+
+	required, err := config.requirementFor(names)
 	if err != nil {
-		return err
+		return nil, nil, &ConfigError{Err: err}
 	}
 
-	return nil
+	return required, config.Filter(required.targets()), nil
 }
 
-func OpenConfig(configFile, defaultTimeout, defaultHTTPTimeout, defaultStatusPattern string, fs afero.Fs) (*Config, error) {
+func OpenConfig(configFile, defaultTimeout, defaultHTTPTimeout, defaultStatusPattern string, fs afero.Fs, overrides ...ConfigOverride) (*Config, error) {
 	var config *Config
 	if configFile == "" {
 		config = NewConfig()
+		for _, override := range overrides {
+			override(config)
+		}
 	} else {
 		f, err := fs.Open(configFile)
 		if err != nil {
-			return nil, fmt.Errorf("unable to open config file: %v", err)
+			return nil, &ConfigError{Err: fmt.Errorf("unable to open config file: %w", err)}
 		}
 
-		config, err = NewConfigFromFile(f)
+		config, err = NewConfigFromFile(f, overrides...)
 		if err != nil {
-			return nil, fmt.Errorf("unable to %v", err)
+			return nil, fmt.Errorf("unable to %w", err)
 		}
 	}
 	timeout, err := time.ParseDuration(defaultTimeout)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse timeout: %v", err)
+		return nil, &ConfigError{Err: fmt.Errorf("unable to parse timeout: %w", err)}
 	}
 	config.DefaultTimeout = timeout
 
 	httpTimeout, err := time.ParseDuration(defaultHTTPTimeout)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse http timeout: %v", err)
+		return nil, &ConfigError{Err: fmt.Errorf("unable to parse http timeout: %w", err)}
 	}
 	config.DefaultHTTPClientTimeout = httpTimeout
 	config.DefaultStatusPattern = defaultStatusPattern
 	return config, nil
 }
 
-func waitOnTargets(logger Logger, targets map[string]TargetConfig, waiters map[string]Waiter) error {
-	var eg errgroup.Group
+func waitOnTargets(ctx context.Context, logger StructuredLogger, targets map[string]TargetConfig, waiters map[string]Waiter) error {
+	_, err := waitOnRequirement(ctx, logger, targets, waiters, requireAll(targets), nil)
+	return err
+}
 
+// checkTargets makes sure that every target can be waited on
+func checkTargets(targets map[string]TargetConfig, waiters map[string]Waiter) error {
 	for name, target := range targets {
-		waiter, found := waiters[target.Type]
-		if !found {
-			return fmt.Errorf("unknown target type %s", target.Type)
+		if _, found := waiters[target.Type]; !found {
+			return &UnknownTypeError{Type: target.Type}
+		}
+		if _, err := newBackoff(&target); err != nil {
+			return &ConfigError{Err: err}
+		}
+		if _, err := withMode(target.Mode, nil); err != nil {
+			return &ConfigError{Err: err}
+		}
+		if err := target.Auth.check(); err != nil {
+			return &ConfigError{Err: err}
+		}
+		if err := target.TLS.check(); err != nil {
+			return &ConfigError{Err: err}
+		}
+		if err := target.compilePatterns(); err != nil {
+			return &ConfigError{Err: err}
+		}
+		targets[name] = target
+	}
+	if err := checkDependencies(targets); err != nil {
+		return &ConfigError{Err: err}
+	}
+
+	return nil
+}
+
+// waitOnRequirement waits on targets until enough of them are ready to meet required, or until
+// so many have failed that it can't be met
+func waitOnRequirement(ctx context.Context, logger StructuredLogger, targets map[string]TargetConfig, waiters map[string]Waiter, required *requirement, events EventHandler) ([]Result, error) {
+	if err := checkTargets(targets, waiters); err != nil {
+		return nil, err
+	}
+
+	waitCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	eg, groupCtx := errgroup.WithContext(waitCtx)
+	progress := newTargetProgress(targets, required)
+	deps := newDependencies(targets)
+
+	// Let the handlers know about every target up front, even those that have to wait for
+	// their dependencies before they are tried
+	var names []string
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		status := StatusWaiting
+		if len(targets[name].DependsOn) > 0 {
+			status = StatusPending
 		}
+		events.emit(Event{Type: EventStatus, Name: name, Status: status})
+	}
 
+	for name, target := range targets {
 		singleName := name
 		singleTarget := target
+		waiter := AsContextWaiter(waiters[target.Type])
 
 		eg.Go(func() error {
-			logger("started waiting for %s", singleName)
-			return waitOnSingleTarget(
-				singleName, logger, singleTarget, waiter,
-			)
+			result := newResult(singleName, singleTarget)
+			err := waitForDependencies(groupCtx, singleName, logger, singleTarget.DependsOn, deps)
+			if err == nil {
+				logger.Info(fmt.Sprintf("started waiting for %s", singleName), targetFields(singleName, &singleTarget)...)
+				if len(singleTarget.DependsOn) > 0 {
+					events.emit(Event{Type: EventStatus, Name: singleName, Status: StatusWaiting})
+				}
+				result, err = waitOnSingleTarget(
+					groupCtx, singleName, logger, singleTarget, waiter, events,
+				)
+			} else if groupCtx.Err() == nil {
+				// A dependency that never became ready means that this target didn't either
+				err = &TimeoutError{Name: singleName, Timeout: singleTarget.Timeout, Err: err}
+				result.Status = StatusFailed
+				result.LastError = err
+			}
+			deps[singleName].finish(err == nil)
+
+			cancelled := groupCtx.Err() != nil
+			if cancelled && err != nil {
+				result.Status = StatusCancelled
+			}
+			events.emit(Event{Type: EventStatus, Name: singleName, Status: result.Status, Err: err})
+
+			state, err := progress.finished(result, err, cancelled)
+			switch state {
+			case requirementMet:
+				stop()
+				return nil
+			case requirementUndecided:
+				if err != nil {
+					logger.Warn(err.Error(), targetFields(singleName, &singleTarget, "error", err)...)
+				}
+				return nil
+			}
+			return err
 		})
 	}
 
 	err := eg.Wait()
 	if err != nil {
-		return err
+		progress.report(logger)
+		if progress.cancelled() {
+			return progress.results(), fmt.Errorf("stopped waiting for %s: %w", strings.Join(progress.notReady(), ", "), ctx.Err())
+		}
+		return progress.results(), err
 	}
 
-	return nil
+	if unneeded := progress.notReady(); len(unneeded) > 0 {
+		logger.Info(fmt.Sprintf("stopped waiting for %s as enough targets are ready", strings.Join(unneeded, ", ")), "targets", unneeded)
+	}
+
+	return progress.results(), nil
 }
 
-func waitOnSingleTarget(name string, logger Logger, target TargetConfig, waiter Waiter) error {
-	end := time.Now().Add(target.Timeout)
+func waitOnSingleTarget(ctx context.Context, name string, logger StructuredLogger, target TargetConfig, waiter ContextWaiter, events EventHandler) (Result, error) {
+	result := newResult(name, target)
+	start := time.Now()
+
+	backoff, err := newBackoff(&target)
+	if err != nil {
+		result.Status = StatusFailed
+		return result, err
+	}
+
+	waiter, err = withMode(target.Mode, waiter)
+	if err != nil {
+		result.Status = StatusFailed
+		return result, err
+	}
+
+	threshold := target.SuccessThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	end := start.Add(target.Timeout)
+
+	failures := 0
+	successes := 0
+	var readySince time.Time
+	for {
+		var pause time.Duration
+
+		result.Attempts++
+		attemptStart := time.Now()
+		err = attemptTarget(ctx, name, &target, waiter)
+		attemptDuration := time.Since(attemptStart)
+		events.emit(Event{
+			Type:     EventAttempt,
+			Name:     name,
+			Attempt:  result.Attempts,
+			Duration: attemptDuration,
+			Err:      err,
+		})
+		fields := targetFields(name, &target, "attempt", result.Attempts, "duration", attemptDuration, "elapsed", time.Since(start))
+		if err != nil {
+			fields = append(fields, "error", err)
+		}
+		logger.Debug(fmt.Sprintf("attempt %d for %s took %s", result.Attempts, name, attemptDuration.Round(time.Millisecond)), fields...)
+		if err == nil {
+			failures = 0
+			successes++
+			if successes == 1 {
+				readySince = time.Now()
+			}
+			if result.FirstSuccess.IsZero() {
+				result.FirstSuccess = readySince
+			}
+
+			readyFor := time.Since(readySince)
+			if successes >= threshold && readyFor >= target.StableFor {
+				break
+			}
+
+			if successes < threshold {
+				logger.Info(
+					fmt.Sprintf("got %d of %d consecutive successes for %s", successes, threshold, name),
+					targetFields(name, &target, "attempt", result.Attempts, "successes", successes, "threshold", threshold)...,
+				)
+				err = fmt.Errorf("only got %d of %d consecutive successes", successes, threshold)
+			} else {
+				logger.Info(
+					fmt.Sprintf("%s has been ready for %s of %s", name, readyFor.Round(time.Millisecond), target.StableFor),
+					targetFields(name, &target, "attempt", result.Attempts, "ready_for", readyFor, "stable_for", target.StableFor)...,
+				)
+				err = fmt.Errorf("only ready for %s of %s", readyFor.Round(time.Millisecond), target.StableFor)
+			}
+			pause = target.interval()
+		} else {
+			successes = 0
+			failures++
+			result.LastError = err
+			err = &ProbeError{Name: name, Attempts: result.Attempts, Err: err}
+			logger.Warn(
+				fmt.Sprintf("error while waiting for %s: %v", name, err),
+				targetFields(name, &target, "attempt", result.Attempts, "elapsed", time.Since(start), "error", err)...,
+			)
+			pause = backoff.Next(failures)
+		}
+
+		if ctx.Err() != nil || !end.After(time.Now()) {
+			break
+		}
+
+		if remaining := time.Until(end); pause > remaining {
+			pause = remaining
+		}
+		if sleepContext(ctx, pause) != nil {
+			break
+		}
+	}
+	result.Duration = time.Since(start)
 
-	err := waiter.Wait(name, &target)
-	for err != nil && end.After(time.Now()) {
-		logger("error while waiting for %s: %v", name, err)
-		time.Sleep(time.Second)
-		err = waiter.Wait(name, &target)
+	if err != nil && ctx.Err() != nil {
+		return result, fmt.Errorf("stopped waiting for %s: %w", name, ctx.Err())
 	}
 
 	if err != nil {
-		return fmt.Errorf("timed out waiting for %s: %v", name, err)
+		result.Status = StatusFailed
+		return result, &TimeoutError{Name: name, Timeout: target.Timeout, Err: err}
+	}
+
+	result.Status = StatusReady
+	logger.Info(
+		fmt.Sprintf("finished waiting for %s", name),
+		targetFields(name, &target, "attempts", result.Attempts, "elapsed", result.Duration)...,
+	)
+
+	return result, nil
+}
+
+// withMode adapts waiter so that it succeeds when the target is in the state described by mode
+func withMode(mode string, waiter ContextWaiter) (ContextWaiter, error) {
+	switch mode {
+	case "", ModeUp:
+		return waiter, nil
+	case ModeDown:
+		return downWaiter{waiter: waiter}, nil
+	default:
+		return nil, fmt.Errorf("unknown mode %s", mode)
+	}
+}
+
+// downWaiter inverts another waiter so that it succeeds once the target stops responding
+type downWaiter struct {
+	waiter ContextWaiter
+}
+
+func (w downWaiter) WaitContext(ctx context.Context, name string, target *TargetConfig) error {
+	err := w.waiter.WaitContext(ctx, name, target)
+	if err == nil {
+		return fmt.Errorf("%s is still available", name)
 	}
 
-	logger("finished waiting for %s", name)
+	// Giving up on the attempt doesn't tell us that the target has gone away
+	if ctx.Err() != nil {
+		return err
+	}
 
 	return nil
 }
 
+// attemptTarget makes a single attempt to reach the target, giving up if it takes longer
+// than the target's attempt timeout
+func attemptTarget(ctx context.Context, name string, target *TargetConfig, waiter ContextWaiter) error {
+	timeout := target.attemptTimeout()
+	if timeout <= 0 {
+		return waiter.WaitContext(ctx, name, target)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := waiter.WaitContext(attemptCtx, name, target)
+	if err != nil && ctx.Err() == nil && attemptCtx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("attempt timed out after %s: %w", timeout, err)
+	}
+	return err
+}
+
+// TCPWaiter checks that a TCP connection can be made to the target
 func TCPWaiter(name string, target *TargetConfig) error {
-	conn, err := net.Dial("tcp", target.Target)
+	return TCPWaiterContext(context.Background(), name, target)
+}
+
+// TCPWaiterContext is the same as TCPWaiter but gives up as soon as ctx is cancelled
+func TCPWaiterContext(ctx context.Context, name string, target *TargetConfig) error {
+	tlsConfig, err := newTLSConfig(target.TLS)
+	if err != nil {
+		return fmt.Errorf("could not connect to %s: %w", name, err)
+	}
+
+	var conn net.Conn
+	if tlsConfig != nil {
+		dialer := &tls.Dialer{Config: tlsConfig}
+		conn, err = dialer.DialContext(ctx, "tcp", target.Target)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", target.Target)
+	}
 	if err != nil {
-		return fmt.Errorf("could not connect to %s: %v", name, err)
+		return fmt.Errorf("could not connect to %s: %w", name, err)
 	}
 	defer conn.Close()
 
 	return nil
 }
 
+// HTTPWaiter checks that the target responds to an HTTP request as expected
 func HTTPWaiter(name string, target *TargetConfig) error {
-	client := &http.Client{
-		Timeout: target.HTTPClientTimeout,
+	return HTTPWaiterContext(context.Background(), name, target)
+}
+
+// HTTPWaiterContext is the same as HTTPWaiter but gives up as soon as ctx is cancelled
+func HTTPWaiterContext(ctx context.Context, name string, target *TargetConfig) error {
+	if !target.compiled {
+		// The target hasn't been checked before waiting, such as when the waiter is used directly
+		compiled := *target
+		if err := compiled.compilePatterns(); err != nil {
+			return err
+		}
+		target = &compiled
+	}
+
+	client, err := newHTTPClient(target)
+	if err != nil {
+		return fmt.Errorf("could not create client for %s: %w", name, err)
+	}
+	req, err := newHTTPRequest(ctx, target)
+	if err != nil {
+		return fmt.Errorf("could not create request for %s: %w", name, err)
 	}
-	req, _ := http.NewRequest("GET", target.Target, nil)
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("could not connect to %s: %v", name, err)
+		return fmt.Errorf("could not connect to %s: %w", name, err)
 	}
-	err = checkStatus(target.StatusPattern, resp.StatusCode)
+	defer resp.Body.Close()
+	err = checkStatus(target.statusPattern, resp.StatusCode)
 	if err != nil {
-		return fmt.Errorf(" %v ", err)
+		return err
 	}
-	return nil
+	err = checkHeaders(target.HTTPExpectHeaders, resp.Header)
+	if err != nil {
+		return err
+	}
+	return checkBody(target.HTTPExpectBody, resp.Body)
 }
 
+// GRPCWaiter checks that a gRPC connection can be made to the target
 func GRPCWaiter(name string, target *TargetConfig) error {
-	ctx, cancel := context.WithTimeout(context.TODO(), target.Timeout)
-	defer cancel()
+	return GRPCWaiterContext(context.Background(), name, target)
+}
+
+// GRPCWaiterContext is the same as GRPCWaiter but gives up as soon as ctx is cancelled
+func GRPCWaiterContext(ctx context.Context, name string, target *TargetConfig) error {
+	// Blocking dials never give up on their own so make sure there is always a deadline
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, target.Timeout)
+		defer cancel()
+	}
+
+	tlsConfig, err := newTLSConfig(target.TLS)
+	if err != nil {
+		return fmt.Errorf("could not connect to %s: %w", name, err)
+	}
+
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
 
 	dialOpts := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(creds),
 		grpc.WithBlock(),
 	}
 	conn, err := grpc.DialContext(ctx, target.Target, dialOpts...)
 	if err != nil {
-		return fmt.Errorf("could not connect to %s: %v", name, err)
+		return fmt.Errorf("could not connect to %s: %w", name, err)
 	}
 	defer conn.Close()
 
 	return nil
 }
 
-// checkStatus checks if the given HTTP status code matches the pattern provided in the target configuration.
-func checkStatus(targetPattern string, code int) error {
-	// Safely compile and initialize  the regular expression pattern and verify if it's valid
-	pattern, err := regexp.Compile(targetPattern)
-	if err != nil {
-		return fmt.Errorf("invalid Regular Expression %v", err)
-	}
+// checkStatus checks if the given HTTP status code matches the pattern compiled from the target configuration.
+func checkStatus(pattern *regexp.Regexp, code int) error {
 	if !pattern.MatchString(strconv.Itoa(code)) {
 		return fmt.Errorf("%d status Code and %s regex didn't match ( -status=<RegexPattern> )", code, pattern.String())
 	}
@@ -197,10 +604,15 @@ type DNSLookup func(host string) ([]net.IP, error)
 
 type DNSWaiter struct {
 	lookup DNSLookup
-	logger Logger
+	logger StructuredLogger
 }
 
 func NewDNSWaiter(lookup DNSLookup, logger Logger) *DNSWaiter {
+	return NewStructuredDNSWaiter(lookup, logger)
+}
+
+// NewStructuredDNSWaiter is the same as NewDNSWaiter but logs with levels and fields to logger
+func NewStructuredDNSWaiter(lookup DNSLookup, logger StructuredLogger) *DNSWaiter {
 	return &DNSWaiter{
 		lookup: lookup,
 		logger: logger,
@@ -228,18 +640,29 @@ func (l IPList) String() string {
 }
 
 func (w *DNSWaiter) Wait(host string, target *TargetConfig) error {
-	in, _ := w.lookup(target.Target)
-	initial := IPList(in)
+	return w.WaitContext(context.Background(), host, target)
+}
+
+// WaitContext waits for the DNS entry to change, giving up as soon as ctx is cancelled
+func (w *DNSWaiter) WaitContext(ctx context.Context, host string, target *TargetConfig) error {
+	initial, _ := w.lookupContext(ctx, target.Target)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	last := initial
 
 	start := time.Now()
 	now := start
 
 	for now.Sub(start) < target.Timeout {
-		w.logger("got DNS result %s", last)
-		time.Sleep(time.Second)
-		l, _ := w.lookup(target.Target)
-		last = IPList(l)
+		w.logger.Info(fmt.Sprintf("got DNS result %s", last), "target", host, "type", "dns", "result", last.String())
+		if err := sleepContext(ctx, time.Second); err != nil {
+			return err
+		}
+		last, _ = w.lookupContext(ctx, target.Target)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		if !initial.Equals(last) {
 			return nil
@@ -248,3 +671,17 @@ func (w *DNSWaiter) Wait(host string, target *TargetConfig) error {
 	}
 	return fmt.Errorf("timed out waiting for DNS update to %s", host)
 }
+
+// lookupContext performs the DNS lookup, returning early if ctx is cancelled
+func (w *DNSWaiter) lookupContext(ctx context.Context, host string) (IPList, error) {
+	var ips []net.IP
+	err := waitWithContext(ctx, func() error {
+		var err error
+		ips, err = w.lookup(host)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return IPList(ips), nil
+}
@@ -1,9 +1,12 @@
 package waitfor
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
+	"regexp"
+	"sync"
 	"testing"
 	"time"
 
@@ -25,22 +28,24 @@ var (
 )
 
 func TestStatusPattern200(t *testing.T) {
-	err := checkStatus("^2..$", 200)
+	err := checkStatus(regexp.MustCompile("^2..$"), 200)
 	assert.Nil(t, err)
 }
 
 func TestInvalidRegex(t *testing.T) {
-	err := checkStatus("[", 200)
+	target := TargetConfig{StatusPattern: "["}
+	err := target.compilePatterns()
 	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid status pattern [")
 }
 
 func TestRegexMatch(t *testing.T) {
-	err := checkStatus("2[0-9]{2}", 200)
+	err := checkStatus(regexp.MustCompile("2[0-9]{2}"), 200)
 	assert.Nil(t, err)
 }
 
 func TestRegexNotMatch(t *testing.T) {
-	err := checkStatus("2[0-9]{2}", 404)
+	err := checkStatus(regexp.MustCompile("2[0-9]{2}"), 404)
 	assert.Error(t, err)
 }
 
@@ -108,11 +113,184 @@ func TestOpenConfig_defaultRegexCanBeSet(t *testing.T) {
 	assert.NotNil(t, config)
 }
 
+func TestOpenConfig_overridesApplyWithoutAFile(t *testing.T) {
+	config, err := OpenConfig("", "5s", "5s", "", afero.NewMemMapFs(), func(c *Config) {
+		c.DefaultBackoff = "exponential"
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "exponential", config.DefaultBackoff)
+}
+
 func TestWaitOn_errorsInvalidTarget(t *testing.T) {
 	err := WaitOn(NewConfig(), NullLogger, []string{"localhost"}, map[string]Waiter{})
 	assert.Error(t, err)
 }
 
+func TestWaitOn_globalTimeoutStopsAllTargets(t *testing.T) {
+	config := NewConfig()
+	config.GlobalTimeout = time.Millisecond * 300
+	config.Targets["ready"] = TargetConfig{Type: "ok", Timeout: time.Second * 10}
+	config.Targets["slow"] = TargetConfig{Type: "fail", Timeout: time.Second * 10}
+
+	start := time.Now()
+	err := WaitOn(config, NullLogger, []string{"ready", "slow"}, map[string]Waiter{
+		"ok":   WaiterFunc(func(string, *TargetConfig) error { return nil }),
+		"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, "global timeout of 300ms exceeded: stopped waiting for slow: context deadline exceeded", err.Error())
+	assert.Less(t, time.Since(start).Seconds(), time.Second.Seconds())
+}
+
+func TestWaitOnResults_reportsEachTarget(t *testing.T) {
+	config := NewConfig()
+	config.Targets["ready"] = TargetConfig{Type: "flaky", Target: "ready-host", Timeout: time.Second, Interval: time.Millisecond}
+	config.Targets["failed"] = TargetConfig{Type: "fail", Target: "failed-host", Timeout: time.Millisecond * 50, Interval: time.Millisecond * 10}
+
+	attempts := 0
+	start := time.Now()
+	results, err := WaitOnResults(context.Background(), config, NullLogger, []string{"ready", "failed"}, map[string]Waiter{
+		"flaky": WaiterFunc(func(string, *TargetConfig) error {
+			attempts++
+			if attempts < 3 {
+				return errors.New("not yet")
+			}
+			return nil
+		}),
+		"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+	})
+
+	require.Error(t, err)
+	require.Len(t, results, 2)
+
+	failed := results[0]
+	assert.Equal(t, "failed", failed.Name)
+	assert.Equal(t, "fail", failed.Type)
+	assert.Equal(t, "failed-host", failed.Target)
+	assert.Equal(t, StatusFailed, failed.Status)
+	assert.Greater(t, failed.Attempts, 1)
+	assert.GreaterOrEqual(t, failed.Duration, time.Millisecond*50)
+	assert.EqualError(t, failed.LastError, "an error")
+	assert.True(t, failed.FirstSuccess.IsZero())
+
+	ready := results[1]
+	assert.Equal(t, "ready", ready.Name)
+	assert.Equal(t, StatusReady, ready.Status)
+	assert.Equal(t, 3, ready.Attempts)
+	assert.EqualError(t, ready.LastError, "not yet")
+	assert.True(t, ready.FirstSuccess.After(start))
+}
+
+func TestWaitOnResults_marksUnfinishedTargetsAsCancelled(t *testing.T) {
+	config := NewConfig()
+	config.Targets["failed"] = TargetConfig{Type: "fail", Timeout: time.Millisecond * 10}
+	config.Targets["slow"] = TargetConfig{Type: "slow", Timeout: time.Second * 10}
+	config.Targets["dependent"] = TargetConfig{Type: "slow", Timeout: time.Second * 10, DependsOn: []string{"slow"}}
+
+	results, err := WaitOnResults(context.Background(), config, NullLogger, []string{"failed", "slow", "dependent"}, map[string]Waiter{
+		"slow": ContextWaiterFunc(func(ctx context.Context, _ string, _ *TargetConfig) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+		"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+	})
+
+	require.Error(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, StatusCancelled, results[0].Status)
+	assert.Equal(t, 0, results[0].Attempts)
+	assert.Equal(t, StatusFailed, results[1].Status)
+	assert.Equal(t, StatusCancelled, results[2].Status)
+	assert.Equal(t, 1, results[2].Attempts)
+}
+
+func TestWaitOnResults_noResultsWhenUnableToStart(t *testing.T) {
+	results, err := WaitOnResults(context.Background(), NewConfig(), NullLogger, []string{"unknown"}, map[string]Waiter{})
+
+	require.Error(t, err)
+	assert.Empty(t, results)
+}
+
+func TestWaitOn_anyTargetStopsWaitingOnOthers(t *testing.T) {
+	config := NewConfig()
+	config.Require = RequireAny
+	config.Targets["ready"] = TargetConfig{Type: "ok", Timeout: time.Second * 10}
+	config.Targets["slow"] = TargetConfig{Type: "fail", Timeout: time.Second * 10}
+
+	start := time.Now()
+	err := WaitOn(config, NullLogger, []string{"ready", "slow"}, map[string]Waiter{
+		"ok":   WaiterFunc(func(string, *TargetConfig) error { return nil }),
+		"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+	})
+
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start).Seconds(), time.Second.Seconds())
+}
+
+func TestWaitOn_quorumToleratesFailures(t *testing.T) {
+	config := NewConfig()
+	config.Require = "2"
+	config.Targets["failed"] = TargetConfig{Type: "fail", Timeout: time.Millisecond * 10}
+	config.Targets["ready 1"] = TargetConfig{Type: "slow", Timeout: time.Second}
+	config.Targets["ready 2"] = TargetConfig{Type: "slow", Timeout: time.Second}
+
+	err := WaitOn(config, NullLogger, []string{"failed", "ready 1", "ready 2"}, map[string]Waiter{
+		"slow": WaiterFunc(func(string, *TargetConfig) error {
+			time.Sleep(time.Millisecond * 100)
+			return nil
+		}),
+		"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+	})
+
+	require.NoError(t, err)
+}
+
+func TestWaitOn_quorumFailsWhenNotEnoughReady(t *testing.T) {
+	config := NewConfig()
+	config.Require = "2"
+	config.Targets["failed 1"] = TargetConfig{Type: "fail", Timeout: time.Millisecond * 10}
+	config.Targets["failed 2"] = TargetConfig{Type: "fail", Timeout: time.Millisecond * 200}
+	config.Targets["ready"] = TargetConfig{Type: "ok", Timeout: time.Second}
+
+	err := WaitOn(config, NullLogger, []string{"failed 1", "failed 2", "ready"}, map[string]Waiter{
+		"ok":   WaiterFunc(func(string, *TargetConfig) error { return nil }),
+		"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, "needed 2 of 3 to be ready: timed out waiting for failed 2: an error", err.Error())
+}
+
+func TestWaitOn_waitsOnGroups(t *testing.T) {
+	config := NewConfig()
+	config.Targets["db"] = TargetConfig{Type: "ok", Timeout: time.Second}
+	config.Targets["replica 1"] = TargetConfig{Type: "fail", Timeout: time.Second * 10}
+	config.Targets["replica 2"] = TargetConfig{Type: "ok", Timeout: time.Second}
+	config.Groups = map[string]GroupConfig{
+		"replicas": {Targets: []string{"replica 1", "replica 2"}, Require: RequireAny},
+	}
+
+	start := time.Now()
+	err := WaitOn(config, NullLogger, []string{"db", "replicas"}, map[string]Waiter{
+		"ok":   WaiterFunc(func(string, *TargetConfig) error { return nil }),
+		"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+	})
+
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start).Seconds(), time.Second.Seconds())
+}
+
+func TestWaitOn_failsWhenQuorumTooLarge(t *testing.T) {
+	config := NewConfig()
+	config.Require = "3"
+
+	err := WaitOn(config, NullLogger, []string{"tcp:localhost:1", "tcp:localhost:2"}, map[string]Waiter{})
+
+	require.Error(t, err)
+	assert.Equal(t, "require of 3 is more than the 2 targets available", err.Error())
+}
+
 func TestRun_errorsOnParseFailure(t *testing.T) {
 	err := WaitOn(NewConfig(), NullLogger, []string{"http://localhost"}, map[string]Waiter{})
 	assert.Error(t, err)
@@ -120,13 +298,15 @@ func TestRun_errorsOnParseFailure(t *testing.T) {
 
 func TestWaitOnSingleTarget_succeedsImmediately(t *testing.T) {
 	var logs []string
-	doLog := func(f string, p ...interface{}) { logs = append(logs, fmt.Sprintf(f, p...)) }
+	var doLog Logger = func(f string, p ...interface{}) { logs = append(logs, fmt.Sprintf(f, p...)) }
 
-	err := waitOnSingleTarget(
+	_, err := waitOnSingleTarget(
+		context.Background(),
 		"name",
 		doLog,
 		TargetConfig{Timeout: time.Second * 2},
 		WaiterFunc(func(name string, target *TargetConfig) error { return nil }),
+		nil,
 	)
 
 	assert.NoError(t, err)
@@ -136,11 +316,12 @@ func TestWaitOnSingleTarget_succeedsImmediately(t *testing.T) {
 
 func TestWaitOnSingleTarget_succeedsAfterWaiting(t *testing.T) {
 	var logs []string
-	doLog := func(f string, p ...interface{}) { logs = append(logs, fmt.Sprintf(f, p...)) }
+	var doLog Logger = func(f string, p ...interface{}) { logs = append(logs, fmt.Sprintf(f, p...)) }
 
 	waitUntil := time.Now().Add(time.Millisecond * 1100)
 
-	err := waitOnSingleTarget(
+	_, err := waitOnSingleTarget(
+		context.Background(),
 		"name",
 		doLog,
 		TargetConfig{Timeout: time.Second * 2},
@@ -150,6 +331,7 @@ func TestWaitOnSingleTarget_succeedsAfterWaiting(t *testing.T) {
 			}
 			return nil
 		}),
+		nil,
 	)
 
 	assert.NoError(t, err)
@@ -159,15 +341,17 @@ func TestWaitOnSingleTarget_succeedsAfterWaiting(t *testing.T) {
 
 func TestWaitOnSingleTarget_failsIfRegexInvalid(t *testing.T) {
 	var logs []string
-	doLog := func(f string, p ...interface{}) { logs = append(logs, fmt.Sprintf(f, p...)) }
+	var doLog Logger = func(f string, p ...interface{}) { logs = append(logs, fmt.Sprintf(f, p...)) }
 
-	err := waitOnSingleTarget(
+	_, err := waitOnSingleTarget(
+		context.Background(),
 		"name",
 		doLog,
 		TargetConfig{StatusPattern: "{5-2}"},
 		WaiterFunc(func(name string, target *TargetConfig) error {
 			return fmt.Errorf("")
 		}),
+		nil,
 	)
 
 	assert.Error(t, err)
@@ -175,23 +359,360 @@ func TestWaitOnSingleTarget_failsIfRegexInvalid(t *testing.T) {
 }
 func TestWaitOnSingleTarget_failsIfTimerExpires(t *testing.T) {
 	var logs []string
-	doLog := func(f string, p ...interface{}) { logs = append(logs, fmt.Sprintf(f, p...)) }
+	var doLog Logger = func(f string, p ...interface{}) { logs = append(logs, fmt.Sprintf(f, p...)) }
 
-	err := waitOnSingleTarget(
+	_, err := waitOnSingleTarget(
+		context.Background(),
 		"name",
 		doLog,
 		TargetConfig{Timeout: time.Second * 2},
 		WaiterFunc(func(name string, target *TargetConfig) error {
 			return fmt.Errorf("")
 		}),
+		nil,
 	)
 
 	assert.Error(t, err)
 	assert.NotContains(t, logs, "finished waiting for name")
 }
 
+func TestWaitOnSingleTarget_pausesForInterval(t *testing.T) {
+	attempts := 0
+
+	_, err := waitOnSingleTarget(
+		context.Background(),
+		"name",
+		NullLogger,
+		TargetConfig{Timeout: time.Millisecond * 500, Interval: time.Millisecond * 100},
+		WaiterFunc(func(name string, target *TargetConfig) error {
+			attempts++
+			return fmt.Errorf("there was an error")
+		}),
+		nil,
+	)
+
+	assert.Error(t, err)
+	assert.GreaterOrEqual(t, attempts, 4)
+	assert.LessOrEqual(t, attempts, 7)
+}
+
+func TestWaitOnSingleTarget_limitsEachAttempt(t *testing.T) {
+	var logs []string
+	var doLog Logger = func(f string, p ...interface{}) { logs = append(logs, fmt.Sprintf(f, p...)) }
+	attempts := 0
+
+	_, err := waitOnSingleTarget(
+		context.Background(),
+		"name",
+		doLog,
+		TargetConfig{
+			Timeout:        time.Millisecond * 900,
+			Interval:       time.Millisecond * 10,
+			AttemptTimeout: time.Millisecond * 200,
+		},
+		ContextWaiterFunc(func(ctx context.Context, name string, target *TargetConfig) error {
+			attempts++
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+		nil,
+	)
+
+	assert.Error(t, err)
+	assert.GreaterOrEqual(t, attempts, 3)
+	assert.Contains(t, logs, "error while waiting for name: attempt timed out after 200ms: context deadline exceeded")
+}
+
+func TestAttemptTarget_httpClientTimeoutOverridesAttemptTimeout(t *testing.T) {
+	var deadline time.Time
+	start := time.Now()
+
+	err := attemptTarget(
+		context.Background(),
+		"name",
+		&TargetConfig{Type: "http", AttemptTimeout: time.Second, HTTPClientTimeout: time.Minute},
+		ContextWaiterFunc(func(ctx context.Context, name string, target *TargetConfig) error {
+			deadline, _ = ctx.Deadline()
+			return nil
+		}),
+	)
+
+	require.NoError(t, err)
+	assert.True(t, deadline.After(start.Add(time.Second*59)))
+}
+
+func TestWaitOnSingleTarget_waitsForSuccessThreshold(t *testing.T) {
+	var logs []string
+	var doLog Logger = func(f string, p ...interface{}) { logs = append(logs, fmt.Sprintf(f, p...)) }
+	results := []error{nil, errors.New("flapping"), nil, nil, nil}
+
+	_, err := waitOnSingleTarget(
+		context.Background(),
+		"name",
+		doLog,
+		TargetConfig{Timeout: time.Second * 2, Interval: time.Millisecond * 10, SuccessThreshold: 3},
+		WaiterFunc(func(name string, target *TargetConfig) error {
+			next := results[0]
+			results = results[1:]
+			return next
+		}),
+		nil,
+	)
+
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	assert.Equal(t, []string{
+		"got 1 of 3 consecutive successes for name",
+		"error while waiting for name: flapping",
+		"got 1 of 3 consecutive successes for name",
+		"got 2 of 3 consecutive successes for name",
+		"finished waiting for name",
+	}, logs)
+}
+
+func TestWaitOnSingleTarget_successResetsBackoff(t *testing.T) {
+	results := []error{errors.New("down"), errors.New("down"), errors.New("down"), nil, errors.New("flapping"), nil, nil}
+
+	start := time.Now()
+	_, err := waitOnSingleTarget(
+		context.Background(),
+		"name",
+		NullLogger,
+		TargetConfig{Timeout: time.Second * 5, Interval: time.Millisecond * 50, Backoff: "exponential", SuccessThreshold: 2},
+		WaiterFunc(func(name string, target *TargetConfig) error {
+			next := results[0]
+			results = results[1:]
+			return next
+		}),
+		nil,
+	)
+
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	// Pauses of 50ms, 100ms, 200ms, 50ms, 50ms and 50ms. The failure after the success
+	// would be followed by 400ms if the backoff carried on from the earlier failures.
+	assert.Less(t, int64(time.Since(start)), int64(time.Millisecond*750))
+}
+
+func TestWaitOnSingleTarget_failsIfThresholdNotReached(t *testing.T) {
+	_, err := waitOnSingleTarget(
+		context.Background(),
+		"name",
+		NullLogger,
+		TargetConfig{Timeout: time.Millisecond * 200, Interval: time.Millisecond * 100, SuccessThreshold: 10},
+		WaiterFunc(func(name string, target *TargetConfig) error { return nil }),
+		nil,
+	)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out waiting for name: only got")
+}
+
+func TestWaitOnSingleTarget_waitsUntilStable(t *testing.T) {
+	var logs []string
+	var doLog Logger = func(f string, p ...interface{}) { logs = append(logs, fmt.Sprintf(f, p...)) }
+
+	start := time.Now()
+	_, err := waitOnSingleTarget(
+		context.Background(),
+		"name",
+		doLog,
+		TargetConfig{Timeout: time.Second * 2, Interval: time.Millisecond * 50, StableFor: time.Millisecond * 300},
+		WaiterFunc(func(name string, target *TargetConfig) error { return nil }),
+		nil,
+	)
+
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*300)
+	assert.Contains(t, logs, "name has been ready for 0s of 300ms")
+	assert.Contains(t, logs, "finished waiting for name")
+}
+
+func TestWaitOnSingleTarget_waitsForTargetToGoDown(t *testing.T) {
+	var logs []string
+	var doLog Logger = func(f string, p ...interface{}) { logs = append(logs, fmt.Sprintf(f, p...)) }
+	results := []error{nil, nil, errors.New("connection refused")}
+
+	_, err := waitOnSingleTarget(
+		context.Background(),
+		"name",
+		doLog,
+		TargetConfig{Timeout: time.Second * 2, Interval: time.Millisecond * 10, Mode: ModeDown},
+		WaiterFunc(func(name string, target *TargetConfig) error {
+			next := results[0]
+			results = results[1:]
+			return next
+		}),
+		nil,
+	)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"error while waiting for name: name is still available",
+		"error while waiting for name: name is still available",
+		"finished waiting for name",
+	}, logs)
+}
+
+func TestWaitOnSingleTarget_downFailsIfTargetStaysUp(t *testing.T) {
+	_, err := waitOnSingleTarget(
+		context.Background(),
+		"name",
+		NullLogger,
+		TargetConfig{Timeout: time.Millisecond * 200, Interval: time.Millisecond * 50, Mode: ModeDown},
+		WaiterFunc(func(name string, target *TargetConfig) error { return nil }),
+		nil,
+	)
+
+	require.Error(t, err)
+	assert.Equal(t, "timed out waiting for name: name is still available", err.Error())
+}
+
+func TestWaitOnSingleTarget_downIgnoresAttemptTimeouts(t *testing.T) {
+	_, err := waitOnSingleTarget(
+		context.Background(),
+		"name",
+		NullLogger,
+		TargetConfig{
+			Timeout:        time.Millisecond * 300,
+			Interval:       time.Millisecond * 10,
+			AttemptTimeout: time.Millisecond * 50,
+			Mode:           ModeDown,
+		},
+		ContextWaiterFunc(func(ctx context.Context, name string, target *TargetConfig) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+		nil,
+	)
+
+	assert.Error(t, err)
+}
+
+func TestWaitOnSingleTarget_failsForUnknownMode(t *testing.T) {
+	_, err := waitOnSingleTarget(
+		context.Background(),
+		"name",
+		NullLogger,
+		TargetConfig{Timeout: time.Second, Mode: "sideways"},
+		WaiterFunc(func(name string, target *TargetConfig) error { return nil }),
+		nil,
+	)
+
+	require.Error(t, err)
+	assert.Equal(t, "unknown mode sideways", err.Error())
+}
+
+func TestTCPWaiter_downSucceedsWhenPortClosed(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	_, err = waitOnSingleTarget(
+		context.Background(),
+		addr,
+		NullLogger,
+		TargetConfig{Target: addr, Type: "tcp", Timeout: time.Second, Mode: ModeDown},
+		ContextWaiterFunc(TCPWaiterContext),
+		nil,
+	)
+
+	assert.NoError(t, err)
+}
+
+func TestWaitOnSingleTarget_failsForUnknownBackoff(t *testing.T) {
+	_, err := waitOnSingleTarget(
+		context.Background(),
+		"name",
+		NullLogger,
+		TargetConfig{Timeout: time.Second, Backoff: "unknown"},
+		WaiterFunc(func(name string, target *TargetConfig) error { return nil }),
+		nil,
+	)
+
+	require.Error(t, err)
+	assert.Equal(t, "unknown backoff unknown", err.Error())
+}
+
+func TestWaitOnSingleTarget_stopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*100, cancel)
+
+	start := time.Now()
+	_, err := waitOnSingleTarget(
+		ctx,
+		"name",
+		NullLogger,
+		TargetConfig{Timeout: time.Second * 10},
+		WaiterFunc(func(name string, target *TargetConfig) error {
+			return fmt.Errorf("there was an error")
+		}),
+		nil,
+	)
+
+	require.Error(t, err)
+	assert.Equal(t, "stopped waiting for name: context canceled", err.Error())
+	assert.Less(t, time.Since(start).Seconds(), time.Second.Seconds())
+}
+
+func TestWaiterFunc_WaitContextReturnsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	time.AfterFunc(time.Millisecond*100, cancel)
+
+	err := WaiterFunc(func(name string, target *TargetConfig) error {
+		<-release
+		return nil
+	}).WaitContext(ctx, "name", &TargetConfig{})
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestAsContextWaiter_keepsContextWaiters(t *testing.T) {
+	var got context.Context
+	ctx := context.WithValue(context.Background(), struct{}{}, "value")
+
+	w := AsContextWaiter(ContextWaiterFunc(func(ctx context.Context, name string, target *TargetConfig) error {
+		got = ctx
+		return nil
+	}))
+
+	require.NoError(t, w.WaitContext(ctx, "name", &TargetConfig{}))
+	assert.Equal(t, ctx, got)
+}
+
+func TestBuiltInWaiters_keepTheirOriginalSignatures(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	waiters := map[string]Waiter{
+		"http": WaiterFunc(HTTPWaiter),
+		"tcp":  WaiterFunc(TCPWaiter),
+		"grpc": WaiterFunc(GRPCWaiter),
+	}
+
+	assert.NoError(t, waiters["tcp"].Wait("name", &TargetConfig{Target: lis.Addr().String()}))
+	assert.NoError(t, TCPWaiter("name", &TargetConfig{Target: lis.Addr().String()}))
+}
+
+func TestTCPWaiter_honoursCancelledContext(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = TCPWaiterContext(ctx, "name", &TargetConfig{Target: lis.Addr().String()})
+	assert.Error(t, err)
+}
+
 func TestWaitOnTargets_failsForUnknownType(t *testing.T) {
 	err := waitOnTargets(
+		context.Background(),
 		NullLogger,
 		map[string]TargetConfig{"unkown": {Type: "unknown type"}},
 		map[string]Waiter{"type": WaiterFunc(func(string, *TargetConfig) error { return errors.New("") })},
@@ -203,6 +724,7 @@ func TestWaitOnTargets_failsForUnknownType(t *testing.T) {
 
 func TestWaitOnTargets_selectsCorrectWaiter(t *testing.T) {
 	err := waitOnTargets(
+		context.Background(),
 		NullLogger,
 		map[string]TargetConfig{
 			"type 1": {Type: "t1"},
@@ -218,6 +740,7 @@ func TestWaitOnTargets_selectsCorrectWaiter(t *testing.T) {
 
 func TestWaitOnTargets_failsWhenWaiterFails(t *testing.T) {
 	err := waitOnTargets(
+		context.Background(),
 		NullLogger,
 		map[string]TargetConfig{
 			"type 1": {Type: "t1"},
@@ -233,6 +756,67 @@ func TestWaitOnTargets_failsWhenWaiterFails(t *testing.T) {
 	assert.Equal(t, "timed out waiting for type 2: an error", err.Error())
 }
 
+func TestWaitOnTargets_cancelsOtherTargetsOnFailure(t *testing.T) {
+	var lock sync.Mutex
+	var logs []string
+	var doLog Logger = func(f string, p ...interface{}) {
+		lock.Lock()
+		defer lock.Unlock()
+		logs = append(logs, fmt.Sprintf(f, p...))
+	}
+
+	start := time.Now()
+	err := waitOnTargets(
+		context.Background(),
+		doLog,
+		map[string]TargetConfig{
+			"ready":   {Type: "ok", Timeout: time.Second * 10},
+			"failing": {Type: "fail", Timeout: time.Millisecond * 500},
+			"pending": {Type: "fail", Timeout: time.Second * 10},
+		},
+		map[string]Waiter{
+			"ok":   WaiterFunc(func(string, *TargetConfig) error { return nil }),
+			"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+		},
+	)
+
+	require.Error(t, err)
+	assert.Equal(t, "timed out waiting for failing: an error", err.Error())
+	assert.Less(t, time.Since(start).Seconds(), (time.Second * 5).Seconds())
+	assert.Contains(t, logs, "stopped waiting as failing failed")
+	assert.Contains(t, logs, "already ready: ready")
+	assert.Contains(t, logs, "still pending: pending")
+}
+
+func TestWaitOnTargets_reportsWhenCancelled(t *testing.T) {
+	var lock sync.Mutex
+	var logs []string
+	var doLog Logger = func(f string, p ...interface{}) {
+		lock.Lock()
+		defer lock.Unlock()
+		logs = append(logs, fmt.Sprintf(f, p...))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*100, cancel)
+
+	err := waitOnTargets(
+		ctx,
+		doLog,
+		map[string]TargetConfig{
+			"pending": {Type: "fail", Timeout: time.Second * 10},
+		},
+		map[string]Waiter{
+			"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+		},
+	)
+
+	require.Error(t, err)
+	assert.Equal(t, "stopped waiting for pending: context canceled", err.Error())
+	assert.Contains(t, logs, "stopped waiting as it was cancelled")
+	assert.Contains(t, logs, "still pending: pending")
+}
+
 func setupGrpcServer(t *testing.T) (*grpc.Server, net.Listener, error) {
 	port, err := freeport.GetFreePort()
 	if err != nil {
@@ -269,11 +853,11 @@ func TestGRPCWaiter_succeedsImmediately(t *testing.T) {
 	}
 	defer server.Stop()
 
-	err = waitOnSingleTarget(lis.Addr().String(), NullLogger, TargetConfig{
+	_, err = waitOnSingleTarget(context.Background(), lis.Addr().String(), NullLogger, TargetConfig{
 		Target:  lis.Addr().String(),
 		Timeout: DefaultTimeout,
 		Type:    "grpc",
-	}, WaiterFunc(GRPCWaiter))
+	}, ContextWaiterFunc(GRPCWaiterContext), nil)
 
 	assert.Nil(t, err, "error waiting for grpc: %v", err)
 }
@@ -301,11 +885,11 @@ func TestGRPCWaiter_failsToConnect(t *testing.T) {
 	}
 	defer server.Stop()
 
-	err = waitOnSingleTarget(lis.Addr().String(), NullLogger, TargetConfig{
+	_, err = waitOnSingleTarget(context.Background(), lis.Addr().String(), NullLogger, TargetConfig{
 		Target:  "localhost:8081",
 		Timeout: DefaultTimeout,
 		Type:    "grpc",
-	}, WaiterFunc(GRPCWaiter))
+	}, ContextWaiterFunc(GRPCWaiterContext), nil)
 
 	assert.NotNil(t, err, "expected error but error was nil")
 	fmt.Println(err)
@@ -360,6 +944,30 @@ func TestDNSWaiter_successAfterDNSChange(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestWaitOnSingleTarget_dnsChangeIsSeenWithAttemptTimeout(t *testing.T) {
+	var lock sync.Mutex
+	lookups := 0
+	w := NewDNSWaiter(func(host string) ([]net.IP, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		lookups++
+		if lookups < 3 {
+			return []net.IP{ip1}, nil
+		}
+		return []net.IP{ip2}, nil
+	}, NullLogger)
+
+	_, err := waitOnSingleTarget(context.Background(), "dns1", NullLogger, TargetConfig{
+		Target:         "dns.name",
+		Type:           "dns",
+		Timeout:        time.Second * 5,
+		AttemptTimeout: time.Millisecond * 500,
+		Interval:       time.Millisecond * 10,
+	}, w, nil)
+
+	require.NoError(t, err)
+}
+
 func TestDNSWaiter_allowsAddressrderChange(t *testing.T) {
 	ips := [][]net.IP{
 		{ip1, ip2, ip3},
@@ -0,0 +1,214 @@
+package waitfor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TargetState is the latest that is known about a target that is being watched
+type TargetState struct {
+	// Name is the name of the target
+	Name string
+	// Type is the kind of target
+	Type string
+	// Target is the location of the target
+	Target string
+	// Ready is true if the target has had enough consecutive successful probes
+	Ready bool
+	// Probes is the number of times that the target has been probed
+	Probes int
+	// ConsecutiveSuccesses is the number of probes in a row that have succeeded
+	ConsecutiveSuccesses int
+	// ConsecutiveFailures is the number of probes in a row that have failed
+	ConsecutiveFailures int
+	// LastProbe is when the target was last probed
+	LastProbe time.Time
+	// LastDuration is how long the last probe took
+	LastDuration time.Duration
+	// LastSuccess is when a probe last succeeded, or the zero time if none have
+	LastSuccess time.Time
+	// LastError is the error from the last probe, or nil if it succeeded
+	LastError error
+}
+
+// Watcher keeps probing targets on their interval, rather than stopping once they are
+// ready, so that it can report on whether they are still available. A target is only
+// ready once it has kept succeeding for its StableFor, and targets aren't probed until
+// the targets that they depend on have been ready.
+type Watcher struct {
+	logger   StructuredLogger
+	events   EventHandler
+	targets  map[string]TargetConfig
+	waiters  map[string]Waiter
+	required *requirement
+	deps     map[string]*dependency
+
+	lock   sync.Mutex
+	states map[string]*TargetState
+	// succeeding is when the current run of successful probes of each target started
+	succeeding map[string]time.Time
+}
+
+// NewWatcher creates a Watcher for the targets and groups named, or every target in config
+// if there are none. Handlers are told about each probe and whenever a target becomes ready
+// or stops being ready.
+func NewWatcher(config *Config, targets []string, waiters map[string]Waiter, logger StructuredLogger, handlers ...EventHandler) (*Watcher, error) {
+	if len(targets) == 0 {
+		for name := range config.Targets {
+			targets = append(targets, name)
+		}
+		sort.Strings(targets)
+	}
+
+	required, filtered, err := resolveTargets(config, targets)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTargets(filtered.Targets, waiters); err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		logger:   logger,
+		events:   eventHandlers(handlers),
+		targets:  filtered.Targets,
+		waiters:  waiters,
+		required: required,
+		deps:     newDependencies(filtered.Targets),
+
+		states:     map[string]*TargetState{},
+		succeeding: map[string]time.Time{},
+	}
+	for name, target := range w.targets {
+		w.states[name] = &TargetState{
+			Name:   name,
+			Type:   target.Type,
+			Target: target.Target,
+		}
+	}
+
+	return w, nil
+}
+
+// Run probes every target until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for name, target := range w.targets {
+		wg.Add(1)
+		go func(name string, target TargetConfig) {
+			defer wg.Done()
+			w.watch(ctx, name, target)
+		}(name, target)
+	}
+	wg.Wait()
+}
+
+// States returns the latest state of each target, sorted by name
+func (w *Watcher) States() []TargetState {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var states []TargetState
+	for _, state := range w.states {
+		states = append(states, *state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+	return states
+}
+
+// Ready returns true if enough of the targets are ready to satisfy the config, taking in
+// to account any require settings
+func (w *Watcher) Ready() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	ready := map[string]bool{}
+	for name, state := range w.states {
+		ready[name] = state.Ready
+	}
+	return w.required.met(ready)
+}
+
+func (w *Watcher) watch(ctx context.Context, name string, target TargetConfig) {
+	waiter, _ := withMode(target.Mode, AsContextWaiter(w.waiters[target.Type]))
+
+	// Each probe needs a limit so that one that hangs doesn't stop the target being watched
+	if target.attemptTimeout() <= 0 {
+		target.AttemptTimeout = target.Timeout
+	}
+
+	if waitForDependencies(ctx, name, w.logger, target.DependsOn, w.deps) != nil {
+		return
+	}
+
+	w.logger.Info(fmt.Sprintf("started watching %s", name), targetFields(name, &target)...)
+	for {
+		start := time.Now()
+		err := attemptTarget(ctx, name, &target, waiter)
+		if ctx.Err() != nil {
+			return
+		}
+		w.record(name, &target, start, time.Since(start), err)
+
+		if sleepContext(ctx, target.interval()) != nil {
+			return
+		}
+	}
+}
+
+// record updates the state of a target after it has been probed
+func (w *Watcher) record(name string, target *TargetConfig, start time.Time, duration time.Duration, err error) {
+	w.lock.Lock()
+	state := w.states[name]
+	wasReady := state.Ready
+
+	state.Probes++
+	state.LastProbe = start
+	state.LastDuration = duration
+	state.LastError = err
+	if err == nil {
+		if state.ConsecutiveSuccesses == 0 {
+			w.succeeding[name] = start.Add(duration)
+		}
+		state.ConsecutiveSuccesses++
+		state.ConsecutiveFailures = 0
+		state.LastSuccess = start.Add(duration)
+	} else {
+		state.ConsecutiveSuccesses = 0
+		state.ConsecutiveFailures++
+	}
+
+	threshold := target.SuccessThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+	readyFor := state.LastSuccess.Sub(w.succeeding[name])
+	state.Ready = state.ConsecutiveSuccesses >= threshold && readyFor >= target.StableFor
+	probes := state.Probes
+	ready := state.Ready
+	w.lock.Unlock()
+
+	if ready {
+		w.deps[name].finish(true)
+	}
+
+	w.events.emit(Event{Type: EventAttempt, Name: name, Attempt: probes, Duration: duration, Err: err})
+
+	switch {
+	case ready && !wasReady:
+		w.logger.Info(fmt.Sprintf("%s is ready", name), targetFields(name, target, "probes", probes)...)
+		w.events.emit(Event{Type: EventStatus, Name: name, Status: StatusReady})
+	case !ready && wasReady:
+		w.logger.Warn(fmt.Sprintf("%s is no longer ready: %v", name, err), targetFields(name, target, "probes", probes, "error", err)...)
+		w.events.emit(Event{Type: EventStatus, Name: name, Status: StatusFailed, Err: err})
+	case err != nil && probes == 1:
+		w.logger.Warn(fmt.Sprintf("%s is not ready: %v", name, err), targetFields(name, target, "probes", probes, "error", err)...)
+	case err != nil:
+		w.logger.Debug(fmt.Sprintf("probe of %s failed: %v", name, err), targetFields(name, target, "probes", probes, "error", err)...)
+	}
+}
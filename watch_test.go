@@ -0,0 +1,198 @@
+package waitfor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWatcher_watchesAllTargetsByDefault(t *testing.T) {
+	config := NewConfig()
+	config.Targets["a"] = TargetConfig{Type: "ok", Target: "a-host"}
+	config.Targets["b"] = TargetConfig{Type: "ok", Target: "b-host"}
+
+	w, err := NewWatcher(config, nil, map[string]Waiter{"ok": WaiterFunc(func(string, *TargetConfig) error { return nil })}, NullLogger)
+
+	require.NoError(t, err)
+	states := w.States()
+	require.Len(t, states, 2)
+	assert.Equal(t, TargetState{Name: "a", Type: "ok", Target: "a-host"}, states[0])
+	assert.Equal(t, "b", states[1].Name)
+}
+
+func TestNewWatcher_failsForUnknownType(t *testing.T) {
+	config := NewConfig()
+	config.Targets["a"] = TargetConfig{Type: "unknown"}
+
+	_, err := NewWatcher(config, []string{"a"}, map[string]Waiter{}, NullLogger)
+
+	var unknown *UnknownTypeError
+	assert.True(t, errors.As(err, &unknown))
+}
+
+func TestWatcher_keepsProbingTargets(t *testing.T) {
+	config := NewConfig()
+	config.Targets["flapping"] = TargetConfig{Type: "flapping", Timeout: time.Second, Interval: time.Millisecond}
+
+	var lock sync.Mutex
+	probes := 0
+	statuses := []Status{}
+	w, err := NewWatcher(config, []string{"flapping"}, map[string]Waiter{
+		"flapping": WaiterFunc(func(string, *TargetConfig) error {
+			lock.Lock()
+			defer lock.Unlock()
+			probes++
+			if probes == 2 || probes > 3 {
+				return errors.New("down")
+			}
+			return nil
+		}),
+	}, NullLogger, func(e Event) {
+		lock.Lock()
+		defer lock.Unlock()
+		if e.Type == EventStatus {
+			statuses = append(statuses, e.Status)
+		}
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for {
+			if s := w.States()[0]; s.Probes >= 6 {
+				cancel()
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	w.Run(ctx)
+
+	state := w.States()[0]
+	assert.False(t, state.Ready)
+	assert.GreaterOrEqual(t, state.ConsecutiveFailures, 3)
+	assert.Equal(t, 0, state.ConsecutiveSuccesses)
+	assert.EqualError(t, state.LastError, "down")
+	assert.False(t, state.LastSuccess.IsZero())
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []Status{StatusReady, StatusFailed, StatusReady, StatusFailed}, statuses)
+}
+
+func TestWatcher_limitsEachProbe(t *testing.T) {
+	config := NewConfig()
+	config.Targets["slow"] = TargetConfig{Type: "slow", Timeout: time.Millisecond * 20, Interval: time.Millisecond}
+
+	w, err := NewWatcher(config, nil, map[string]Waiter{
+		"slow": ContextWaiterFunc(func(ctx context.Context, _ string, _ *TargetConfig) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	}, NullLogger)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	w.Run(ctx)
+
+	state := w.States()[0]
+	assert.GreaterOrEqual(t, state.Probes, 2)
+	assert.Contains(t, state.LastError.Error(), "attempt timed out after 20ms")
+}
+
+func TestWatcher_readyOnceStable(t *testing.T) {
+	config := NewConfig()
+	config.Targets["unstable"] = TargetConfig{Type: "ok", Timeout: time.Second, Interval: time.Millisecond, StableFor: time.Hour}
+	config.Targets["stable"] = TargetConfig{Type: "ok", Timeout: time.Second, Interval: time.Millisecond, StableFor: time.Millisecond * 20}
+
+	w, err := NewWatcher(config, nil, map[string]Waiter{
+		"ok": WaiterFunc(func(string, *TargetConfig) error { return nil }),
+	}, NullLogger)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	w.Run(ctx)
+
+	states := w.States()
+	assert.Equal(t, "stable", states[0].Name)
+	assert.True(t, states[0].Ready)
+	assert.Equal(t, "unstable", states[1].Name)
+	assert.GreaterOrEqual(t, states[1].ConsecutiveSuccesses, 2)
+	assert.False(t, states[1].Ready)
+}
+
+func TestWatcher_waitsForDependencies(t *testing.T) {
+	config := NewConfig()
+	config.Targets["api"] = TargetConfig{Type: "ok", Timeout: time.Second, Interval: time.Millisecond, DependsOn: []string{"db"}}
+	config.Targets["db"] = TargetConfig{Type: "fail", Timeout: time.Second, Interval: time.Millisecond}
+	config.Targets["cache"] = TargetConfig{Type: "ok", Timeout: time.Second, Interval: time.Millisecond}
+	config.Targets["worker"] = TargetConfig{Type: "ok", Timeout: time.Second, Interval: time.Millisecond, DependsOn: []string{"cache"}}
+
+	w, err := NewWatcher(config, nil, map[string]Waiter{
+		"ok":   WaiterFunc(func(string, *TargetConfig) error { return nil }),
+		"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+	}, NullLogger)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	w.Run(ctx)
+
+	probes := map[string]int{}
+	for _, state := range w.States() {
+		probes[state.Name] = state.Probes
+	}
+	assert.Greater(t, probes["db"], 0)
+	assert.Equal(t, 0, probes["api"])
+	assert.Greater(t, probes["worker"], 0)
+}
+
+func TestWatcher_readyWhenRequirementMet(t *testing.T) {
+	config := NewConfig()
+	config.Require = RequireAny
+	config.Targets["up"] = TargetConfig{Type: "ok", Timeout: time.Second, Interval: time.Hour}
+	config.Targets["down"] = TargetConfig{Type: "fail", Timeout: time.Second, Interval: time.Hour}
+
+	w, err := NewWatcher(config, []string{"up", "down"}, map[string]Waiter{
+		"ok":   WaiterFunc(func(string, *TargetConfig) error { return nil }),
+		"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+	}, NullLogger)
+	require.NoError(t, err)
+	assert.False(t, w.Ready())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for w.States()[0].Probes == 0 || w.States()[1].Probes == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	w.Run(ctx)
+
+	assert.True(t, w.Ready())
+
+	config.Require = RequireAll
+	w, err = NewWatcher(config, []string{"up", "down"}, map[string]Waiter{
+		"ok":   WaiterFunc(func(string, *TargetConfig) error { return nil }),
+		"fail": WaiterFunc(func(string, *TargetConfig) error { return errors.New("an error") }),
+	}, NullLogger)
+	require.NoError(t, err)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		for w.States()[0].Probes == 0 || w.States()[1].Probes == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	w.Run(ctx)
+
+	assert.False(t, w.Ready())
+}